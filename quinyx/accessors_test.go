@@ -0,0 +1,106 @@
+package quinyx
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+// TestAccessors fails when a pointer field of a model, an exported struct with json
+// or url tags, has no GetX accessor. Run go generate to add them.
+func TestAccessors(t *testing.T) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, ".", func(fi os.FileInfo) bool {
+		return !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	assert.NilError(t, err)
+	pkg := pkgs["quinyx"]
+	assert.Assert(t, pkg != nil)
+
+	methods := map[string]bool{}
+	for _, f := range pkg.Files {
+		for _, decl := range f.Decls {
+			fd, ok := decl.(*ast.FuncDecl)
+			if !ok || fd.Recv == nil || len(fd.Recv.List) != 1 {
+				continue
+			}
+			recv := fd.Recv.List[0].Type
+			if se, ok := recv.(*ast.StarExpr); ok {
+				recv = se.X
+			}
+			if id, ok := recv.(*ast.Ident); ok {
+				methods[id.Name+"."+fd.Name.Name] = true
+			}
+		}
+	}
+
+	checked := 0
+	for filename, f := range pkg.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			ts, ok := n.(*ast.TypeSpec)
+			if !ok || !ts.Name.IsExported() {
+				return true
+			}
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok || !isTaggedModel(st) {
+				return true
+			}
+			for _, field := range st.Fields.List {
+				if _, ok := field.Type.(*ast.StarExpr); !ok {
+					continue
+				}
+				for _, name := range field.Names {
+					if !name.IsExported() {
+						continue
+					}
+					checked++
+					assert.Check(t, methods[ts.Name.Name+".Get"+name.Name], "%s: %s.%s has no Get%s accessor, run go generate", filename, ts.Name.Name, name.Name, name.Name)
+				}
+			}
+			return true
+		})
+	}
+	assert.Assert(t, checked > 0)
+}
+
+func isTaggedModel(st *ast.StructType) bool {
+	for _, field := range st.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		tag := reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+		if _, ok := tag.Lookup("json"); ok {
+			return true
+		}
+		if _, ok := tag.Lookup("url"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func TestAccessorsNilSafe(t *testing.T) {
+	var tag *Tag
+	assert.Equal(t, "", tag.GetName())
+	assert.Equal(t, false, tag.GetUniqueScheduling())
+	assert.Assert(t, tag.GetStartDate().IsZero())
+
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	tag = &Tag{Name: String("n"), StartDate: &Timestamp{start}, UniqueScheduling: Bool(true)}
+	assert.Equal(t, "n", tag.GetName())
+	assert.Equal(t, "", tag.GetCode())
+	assert.Equal(t, true, tag.GetUniqueScheduling())
+	assert.Assert(t, tag.GetStartDate().Time.Equal(start))
+
+	var f *GeoJSONFeature
+	assert.Assert(t, f.GetGeometry() == nil)
+	var c *Coordinate
+	assert.Equal(t, float64(0), c.GetLatitude())
+}
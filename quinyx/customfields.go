@@ -0,0 +1,260 @@
+package quinyx
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// customFieldDateFormat is the default format of time.Time custom fields
+const customFieldDateFormat = "2006-01-02"
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	textMarshalerType   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// CustomFieldError is the error returned when a struct field cannot be converted to or from a custom field
+type CustomFieldError struct {
+	// Field is the name of the struct field
+	Field string
+	Label string
+	Value string
+	Err   error
+}
+
+func (e *CustomFieldError) Error() string {
+	if e.Value != "" {
+		return fmt.Sprintf("custom field %s (%q): value %q: %v", e.Field, e.Label, e.Value, e.Err)
+	}
+	return fmt.Sprintf("custom field %s (%q): %v", e.Field, e.Label, e.Err)
+}
+
+func (e *CustomFieldError) Unwrap() error {
+	return e.Err
+}
+
+// customFieldSpec is the parsed quinyx struct tag of a field
+type customFieldSpec struct {
+	index     int
+	name      string
+	label     string
+	omitempty bool
+	required  bool
+	format    string
+	enum      []string
+}
+
+// parseCustomFieldSpecs reads the quinyx struct tags of t. The tag is a comma separated
+// list of label=..., format=..., enum=a|b|c, omitempty and required. Fields without
+// a label use their name, fields tagged "-" and unexported fields are skipped.
+func parseCustomFieldSpecs(t reflect.Type) ([]*customFieldSpec, error) {
+	var specs []*customFieldSpec
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("quinyx")
+		if f.PkgPath != "" || tag == "-" {
+			continue
+		}
+		spec := &customFieldSpec{index: i, name: f.Name, label: f.Name, format: customFieldDateFormat}
+		for _, opt := range strings.Split(tag, ",") {
+			opt = strings.TrimSpace(opt)
+			key, value := opt, ""
+			if j := strings.Index(opt, "="); j >= 0 {
+				key, value = opt[:j], opt[j+1:]
+			}
+			switch key {
+			case "":
+			case "label":
+				spec.label = value
+			case "format":
+				spec.format = value
+			case "enum":
+				spec.enum = strings.Split(value, "|")
+			case "omitempty":
+				spec.omitempty = true
+			case "required":
+				spec.required = true
+			default:
+				return nil, fmt.Errorf("field %s: unknown quinyx tag option %q", f.Name, key)
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+func (s *customFieldSpec) checkEnum(value string) error {
+	if len(s.enum) == 0 {
+		return nil
+	}
+	for _, e := range s.enum {
+		if value == e {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of %s", strings.Join(s.enum, ", "))
+}
+
+// MarshalCustomFields converts the fields of the struct v points to into custom fields,
+// using the labels of their quinyx struct tags, e.g.
+//
+//	type Metadata struct {
+//		GLAccount int       `quinyx:"label=GL Account"`
+//		Owner     string    `quinyx:"label=Budget owner,omitempty"`
+//		Region    string    `quinyx:"label=Region,enum=north|south"`
+//		Opened    time.Time `quinyx:"label=Opened,format=2006-01-02"`
+//	}
+//
+// Strings, ints, floats, bools, time.Time, types implementing encoding.TextMarshaler
+// and pointers to them are supported. Nil pointers are left out.
+func MarshalCustomFields(v interface{}) ([]*CustomField, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("MarshalCustomFields needs a struct, got %T", v)
+	}
+	specs, err := parseCustomFieldSpecs(rv.Type())
+	if err != nil {
+		return nil, err
+	}
+	var fields []*CustomField
+	for _, spec := range specs {
+		fv := rv.Field(spec.index)
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		}
+		if spec.omitempty && fv.IsZero() {
+			continue
+		}
+		value, err := formatCustomField(fv, spec)
+		if err == nil {
+			err = spec.checkEnum(value)
+		}
+		if err != nil {
+			return nil, &CustomFieldError{Field: spec.name, Label: spec.label, Value: value, Err: err}
+		}
+		fields = append(fields, &CustomField{Label: String(spec.label), Value: String(value)})
+	}
+	return fields, nil
+}
+
+func formatCustomField(v reflect.Value, spec *customFieldSpec) (string, error) {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(spec.format), nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// UnmarshalCustomFields sets the fields of the struct v points to from the custom fields
+// with their labels, see MarshalCustomFields. Labels without a field are ignored and
+// fields without a custom field are left as they are, unless they are tagged required.
+func UnmarshalCustomFields(fields []*CustomField, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("UnmarshalCustomFields needs a pointer to a struct, got %T", v)
+	}
+	rv = rv.Elem()
+	specs, err := parseCustomFieldSpecs(rv.Type())
+	if err != nil {
+		return err
+	}
+	values := map[string]string{}
+	for _, cf := range fields {
+		if cf != nil && cf.Label != nil {
+			values[*cf.Label] = stringValue(cf.Value)
+		}
+	}
+	for _, spec := range specs {
+		value, ok := values[spec.label]
+		if !ok {
+			if spec.required {
+				return &CustomFieldError{Field: spec.name, Label: spec.label, Err: fmt.Errorf("is required")}
+			}
+			continue
+		}
+		if err := spec.checkEnum(value); err != nil {
+			return &CustomFieldError{Field: spec.name, Label: spec.label, Value: value, Err: err}
+		}
+		fv := rv.Field(spec.index)
+		if fv.Kind() == reflect.Ptr {
+			p := reflect.New(fv.Type().Elem())
+			if err := parseCustomField(p.Elem(), value, spec); err != nil {
+				return &CustomFieldError{Field: spec.name, Label: spec.label, Value: value, Err: err}
+			}
+			fv.Set(p)
+			continue
+		}
+		if err := parseCustomField(fv, value, spec); err != nil {
+			return &CustomFieldError{Field: spec.name, Label: spec.label, Value: value, Err: err}
+		}
+	}
+	return nil
+}
+
+func parseCustomField(v reflect.Value, value string, spec *customFieldSpec) error {
+	if v.Type() == timeType {
+		t, err := time.Parse(spec.format, value)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if reflect.PtrTo(v.Type()).Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(strings.TrimSpace(value), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(strings.TrimSpace(value), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package quinyx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+type testRegion int
+
+func (r testRegion) MarshalText() ([]byte, error) {
+	switch r {
+	case 1:
+		return []byte("north"), nil
+	case 2:
+		return []byte("south"), nil
+	}
+	return nil, fmt.Errorf("unknown region %d", int(r))
+}
+
+func (r *testRegion) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "north":
+		*r = 1
+	case "south":
+		*r = 2
+	default:
+		return fmt.Errorf("unknown region %q", string(b))
+	}
+	return nil
+}
+
+type testMetadata struct {
+	GLAccount int        `quinyx:"label=GL Account,required"`
+	Owner     string     `quinyx:"label=Budget owner,omitempty"`
+	Share     float64    `quinyx:"label=Share"`
+	Active    bool       `quinyx:"label=Active"`
+	Opened    time.Time  `quinyx:"label=Opened"`
+	Closes    *time.Time `quinyx:"label=Closes,format=02/01/2006"`
+	Region    testRegion `quinyx:"label=Region"`
+	Tier      string     `quinyx:"label=Tier,enum=gold|silver"`
+	Note      string
+	skipped   string
+	Ignored   string `quinyx:"-"`
+}
+
+func TestMarshalCustomFields(t *testing.T) {
+	m := &testMetadata{
+		GLAccount: 4010,
+		Share:     0.25,
+		Active:    true,
+		Opened:    time.Date(2020, time.October, 12, 0, 0, 0, 0, time.UTC),
+		Region:    2,
+		Tier:      "gold",
+		Note:      "n",
+		skipped:   "s",
+		Ignored:   "i",
+	}
+	fields, err := MarshalCustomFields(m)
+	assert.NilError(t, err)
+	var pairs []string
+	for _, f := range fields {
+		pairs = append(pairs, *f.Label+"="+*f.Value)
+	}
+	assert.Equal(t, "GL Account=4010;Share=0.25;Active=true;Opened=2020-10-12;Region=south;Tier=gold;Note=n", strings.Join(pairs, ";"))
+
+	var back testMetadata
+	closes := time.Date(2021, time.January, 31, 0, 0, 0, 0, time.UTC)
+	fields = append(fields, &CustomField{Label: String("Closes"), Value: String("31/01/2021")}, &CustomField{Label: String("Unknown"), Value: String("x")})
+	assert.NilError(t, UnmarshalCustomFields(fields, &back))
+	assert.Equal(t, closes, *back.Closes)
+	m.skipped, m.Ignored, back.Closes = "", "", nil
+	assert.Assert(t, *m == back)
+
+	m.Tier = "bronze"
+	_, err = MarshalCustomFields(m)
+	assert.ErrorContains(t, err, `custom field Tier ("Tier"): value "bronze": must be one of gold, silver`)
+}
+
+func TestUnmarshalCustomFieldsErrors(t *testing.T) {
+	var m testMetadata
+	err := UnmarshalCustomFields([]*CustomField{{Label: String("GL Account"), Value: String("x12")}}, &m)
+	var cerr *CustomFieldError
+	assert.Assert(t, errors.As(err, &cerr))
+	assert.Equal(t, "GLAccount", cerr.Field)
+	assert.Assert(t, errors.Is(err, strconv.ErrSyntax))
+
+	err = UnmarshalCustomFields(nil, &m)
+	assert.ErrorContains(t, err, `custom field GLAccount ("GL Account"): is required`)
+
+	err = UnmarshalCustomFields([]*CustomField{{Label: String("GL Account"), Value: String("1")}, {Label: String("Region"), Value: String("east")}}, &m)
+	assert.ErrorContains(t, err, `custom field Region ("Region"): value "east": unknown region "east"`)
+
+	assert.ErrorContains(t, UnmarshalCustomFields(nil, m), "needs a pointer to a struct")
+}
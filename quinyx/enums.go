@@ -0,0 +1,64 @@
+package quinyx
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// enum is implemented by the string enums of the Quinyx models
+type enum interface {
+	IsKnown() bool
+}
+
+var enumType = reflect.TypeOf((*enum)(nil)).Elem()
+
+// UnknownEnumError is the error returned by Do in strict mode, see Client.StrictEnums,
+// when a response holds an enum value this library does not know
+type UnknownEnumError struct {
+	// Type is the name of the enum type, like TagType
+	Type  string
+	Value string
+}
+
+func (e *UnknownEnumError) Error() string {
+	return fmt.Sprintf("unknown %s %q", e.Type, e.Value)
+}
+
+// checkEnums walks the decoded value and returns an *UnknownEnumError for the first
+// non-empty enum that is not known. Empty values are fields Quinyx left out.
+func checkEnums(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return checkEnums(v.Elem())
+	case reflect.String:
+		if v.Type().Implements(enumType) && v.Len() > 0 && !v.Interface().(enum).IsKnown() {
+			return &UnknownEnumError{Type: v.Type().Name(), Value: v.String()}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				continue // unexported
+			}
+			if err := checkEnums(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := checkEnums(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := checkEnums(iter.Value()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package quinyx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestEnumsIsKnown(t *testing.T) {
+	assert.Assert(t, Project.IsKnown())
+	assert.Assert(t, !TagType("BUDGET").IsKnown())
+	assert.Assert(t, PeriodTypeWeeks.IsKnown())
+	assert.Assert(t, !PeriodType("MONTHS").IsKnown())
+	assert.Assert(t, Sunday.IsKnown())
+	assert.Assert(t, !Weekday("7").IsKnown())
+}
+
+func TestUnknownEnumRoundTrip(t *testing.T) {
+	var c TagCategory
+	assert.NilError(t, json.Unmarshal([]byte(`{"externalId":"b","tagType":"BUDGET"}`), &c))
+	assert.Equal(t, TagType("BUDGET"), c.TagType)
+	b, err := json.Marshal(&c)
+	assert.NilError(t, err)
+	assert.Equal(t, `{"externalId":"b","tagType":"BUDGET"}`, string(b))
+
+	assert.ErrorContains(t, json.Unmarshal([]byte(`{"tagType":12}`), &c), "cannot unmarshal number")
+}
+
+func TestStrictEnums(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/tags/categories", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"externalId":"cc","tagType":"COST_CENTER"},{"externalId":"b","tagType":"BUDGET"}]`)
+	})
+	mux.HandleFunc("/tags/categories/b/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"externalId":"t","periods":[{"type":"MONTHS","from":"2021-01-01T00:00:00Z"}]}]`)
+	})
+
+	cats, _, err := client.Tags.GetAllCategories(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, TagType("BUDGET"), cats[1].TagType)
+	tags, _, err := client.Tags.GetAllTags(context.Background(), "b")
+	assert.NilError(t, err)
+	assert.Equal(t, PeriodType("MONTHS"), tags[0].Periods[0].Type)
+
+	client.StrictEnums = true
+	_, _, err = client.Tags.GetAllCategories(context.Background())
+	assert.Error(t, err, `unknown TagType "BUDGET"`)
+	_, _, err = client.Tags.GetAllTags(context.Background(), "b")
+	uerr, ok := err.(*UnknownEnumError)
+	assert.Assert(t, ok, "%T", err)
+	assert.Equal(t, "PeriodType", uerr.Type)
+	assert.Equal(t, "MONTHS", uerr.Value)
+}
+
+func TestUpdateTagUnknownPeriodType(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/tags/categories/b/tags/t", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PUT" {
+			var tag Tag
+			assert.NilError(t, json.NewDecoder(r.Body).Decode(&tag))
+			assert.Equal(t, PeriodType("MONTHS"), tag.Periods[0].Type)
+		}
+		fmt.Fprint(w, `{"categoryExternalId":"b","externalId":"t","periods":[{"type":"MONTHS","count":1,"from":"2021-01-01T00:00:00Z"}]}`)
+	})
+
+	tag, _, err := client.Tags.GetTag(context.Background(), "b", "t")
+	assert.NilError(t, err)
+	tag.Name = String("renamed")
+	_, _, err = client.Tags.UpdateTag(context.Background(), "b", "t", tag)
+	assert.NilError(t, err)
+
+	client.StrictEnums = true
+	_, _, err = client.Tags.UpdateTag(context.Background(), "b", "t", tag)
+	assert.Error(t, err, `unknown PeriodType "MONTHS"`)
+}
@@ -0,0 +1,228 @@
+package quinyx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFanOutConcurrency = 8
+	defaultFanOutRetries     = 3
+	defaultRateLimitBackoff  = time.Second
+)
+
+// UnitSection identifies a unit and optionally one of its sections
+type UnitSection struct {
+	ExternalUnitID    string
+	ExternalSectionID *string
+}
+
+// Key is the key of the unit in fan-out results, the unit ID or unit/section
+func (u UnitSection) Key() string {
+	if u.ExternalSectionID != nil {
+		return u.ExternalUnitID + "/" + *u.ExternalSectionID
+	}
+	return u.ExternalUnitID
+}
+
+// FanOutOptions configures the multi-unit read helpers
+type FanOutOptions struct {
+	// Concurrency is the maximum number of calls in flight, defaults to 8
+	Concurrency int
+	// MaxRetries is the number of times a rate limited call is retried, defaults to 3.
+	// A negative value disables retries.
+	MaxRetries int
+}
+
+// AggregatedDataByUnit holds the results of GetAggregatedDataForUnits keyed by UnitSection.Key
+type AggregatedDataByUnit struct {
+	Results map[string][]*AggregatedPayload
+	Errors  map[string]error
+}
+
+// CalculatedForecastByUnit holds the results of GetCalculatedForecastForUnits keyed by UnitSection.Key
+type CalculatedForecastByUnit struct {
+	Results map[string][]*CalculatedForecast
+	Errors  map[string]error
+}
+
+// DataProvidersByUnit holds the results of GetActualDataStreamForUnits and GetForecastDataForUnits keyed by UnitSection.Key
+type DataProvidersByUnit struct {
+	Results map[string][]*DataProvider
+	Errors  map[string]error
+}
+
+// GetAggregatedDataForUnits calls GetAggregatedData for every unit, using the time range of template.
+// Units that fail are in Errors. When ctx is canceled the results collected so far are
+// returned together with ctx.Err(), and the units that never completed get ctx.Err() in Errors.
+func (s *ForecastService) GetAggregatedDataForUnits(ctx context.Context, externalForecastVariableID string, units []UnitSection, template *RequestRangeOptions, opts *FanOutOptions) (*AggregatedDataByUnit, error) {
+	res := &AggregatedDataByUnit{Results: map[string][]*AggregatedPayload{}}
+	var mu sync.Mutex
+	errs, err := s.fanOut(ctx, units, template, opts, func(ctx context.Context, key string, o *RequestRangeOptions) error {
+		data, _, err := s.GetAggregatedData(ctx, externalForecastVariableID, o)
+		if err == nil {
+			mu.Lock()
+			res.Results[key] = data
+			mu.Unlock()
+		}
+		return err
+	})
+	res.Errors = errs
+	return res, err
+}
+
+// GetCalculatedForecastForUnits calls GetCalculatedForecast for every unit, see GetAggregatedDataForUnits
+func (s *ForecastService) GetCalculatedForecastForUnits(ctx context.Context, externalForecastVariableID string, units []UnitSection, template *RequestRangeOptions, opts *FanOutOptions) (*CalculatedForecastByUnit, error) {
+	res := &CalculatedForecastByUnit{Results: map[string][]*CalculatedForecast{}}
+	var mu sync.Mutex
+	errs, err := s.fanOut(ctx, units, template, opts, func(ctx context.Context, key string, o *RequestRangeOptions) error {
+		data, _, err := s.GetCalculatedForecast(ctx, externalForecastVariableID, o)
+		if err == nil {
+			mu.Lock()
+			res.Results[key] = data
+			mu.Unlock()
+		}
+		return err
+	})
+	res.Errors = errs
+	return res, err
+}
+
+// GetActualDataStreamForUnits calls GetActualDataStream for every unit, see GetAggregatedDataForUnits
+func (s *ForecastService) GetActualDataStreamForUnits(ctx context.Context, externalForecastVariableID string, units []UnitSection, template *RequestRangeOptions, opts *FanOutOptions) (*DataProvidersByUnit, error) {
+	return s.dataProvidersForUnits(ctx, units, template, opts, func(ctx context.Context, o *RequestRangeOptions) ([]*DataProvider, error) {
+		data, _, err := s.GetActualDataStream(ctx, externalForecastVariableID, o)
+		return data, err
+	})
+}
+
+// GetForecastDataForUnits calls GetForecastData for every unit, see GetAggregatedDataForUnits
+func (s *ForecastService) GetForecastDataForUnits(ctx context.Context, externalForecastVariableID string, units []UnitSection, template *RequestRangeOptions, opts *FanOutOptions) (*DataProvidersByUnit, error) {
+	return s.dataProvidersForUnits(ctx, units, template, opts, func(ctx context.Context, o *RequestRangeOptions) ([]*DataProvider, error) {
+		data, _, err := s.GetForecastData(ctx, externalForecastVariableID, o)
+		return data, err
+	})
+}
+
+func (s *ForecastService) dataProvidersForUnits(ctx context.Context, units []UnitSection, template *RequestRangeOptions, opts *FanOutOptions, get func(context.Context, *RequestRangeOptions) ([]*DataProvider, error)) (*DataProvidersByUnit, error) {
+	res := &DataProvidersByUnit{Results: map[string][]*DataProvider{}}
+	var mu sync.Mutex
+	errs, err := s.fanOut(ctx, units, template, opts, func(ctx context.Context, key string, o *RequestRangeOptions) error {
+		data, err := get(ctx, o)
+		if err == nil {
+			mu.Lock()
+			res.Results[key] = data
+			mu.Unlock()
+		}
+		return err
+	})
+	res.Errors = errs
+	return res, err
+}
+
+// fanOut runs call for every unit on a bounded pool of workers. Rate limited calls
+// pause all workers for the Retry-After of the response before being retried.
+func (s *ForecastService) fanOut(ctx context.Context, units []UnitSection, template *RequestRangeOptions, opts *FanOutOptions, call func(ctx context.Context, key string, o *RequestRangeOptions) error) (map[string]error, error) {
+	if template == nil || template.StartTime.IsZero() || template.EndTime.IsZero() {
+		return nil, ErrorReqfieldsMissing
+	}
+	concurrency, retries := defaultFanOutConcurrency, defaultFanOutRetries
+	if opts != nil {
+		if opts.Concurrency > 0 {
+			concurrency = opts.Concurrency
+		}
+		if opts.MaxRetries != 0 {
+			retries = opts.MaxRetries
+		}
+	}
+
+	errs := map[string]error{}
+	var mu sync.Mutex
+	setErr := func(key string, err error) {
+		mu.Lock()
+		errs[key] = err
+		mu.Unlock()
+	}
+
+	gate := &rateGate{}
+	jobs := make(chan UnitSection)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(units); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range jobs {
+				o := *template
+				o.ExternalUnitID = String(u.ExternalUnitID)
+				o.ExternalSectionID = u.ExternalSectionID
+				for attempt := 0; ; attempt++ {
+					if err := gate.wait(ctx); err != nil {
+						setErr(u.Key(), err)
+						break
+					}
+					err := call(ctx, u.Key(), &o)
+					if rerr, ok := err.(*RateLimitError); ok && attempt < retries {
+						gate.pause(rerr.RetryAfter)
+						continue
+					}
+					if err != nil {
+						setErr(u.Key(), err)
+					}
+					break
+				}
+			}
+		}()
+	}
+
+	canceled := false
+	for _, u := range units {
+		if canceled {
+			setErr(u.Key(), ctx.Err())
+			continue
+		}
+		select {
+		case jobs <- u:
+		case <-ctx.Done():
+			canceled = true
+			setErr(u.Key(), ctx.Err())
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return errs, ctx.Err()
+}
+
+// rateGate holds back all fan-out workers while Quinyx rate limits us
+type rateGate struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+func (g *rateGate) pause(d time.Duration) {
+	if d <= 0 {
+		d = defaultRateLimitBackoff
+	}
+	g.mu.Lock()
+	if until := time.Now().Add(d); until.After(g.until) {
+		g.until = until
+	}
+	g.mu.Unlock()
+}
+
+func (g *rateGate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	d := time.Until(g.until)
+	g.mu.Unlock()
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
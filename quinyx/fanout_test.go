@@ -0,0 +1,114 @@
+package quinyx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func testUnits(ids ...string) []UnitSection {
+	units := make([]UnitSection, len(ids))
+	for i, id := range ids {
+		units[i] = UnitSection{ExternalUnitID: id}
+	}
+	return units
+}
+
+func testRangeTemplate() *RequestRangeOptions {
+	return &RequestRangeOptions{
+		StartTime: time.Date(2020, time.October, 12, 0, 0, 0, 0, time.UTC),
+		EndTime:   time.Date(2020, time.October, 13, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestGetAggregatedDataForUnits(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var inFlight, maxInFlight int32
+	mux.HandleFunc("/forecasts/forecast-variables/v/aggregated-data", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			m := atomic.LoadInt32(&maxInFlight)
+			if n <= m || atomic.CompareAndSwapInt32(&maxInFlight, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		unit := r.URL.Query().Get("externalUnitId")
+		if unit == "broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		assert.Equal(t, "2020-10-12T00:00:00Z", r.URL.Query().Get("startTime"))
+		fmt.Fprintf(w, `[{"data":%d}]`, len(unit))
+	})
+
+	units := append(testUnits("a", "bb", "ccc", "dddd", "broken"), UnitSection{ExternalUnitID: "a", ExternalSectionID: String("s")})
+	res, err := client.Forecast.GetAggregatedDataForUnits(context.Background(), "v", units, testRangeTemplate(), &FanOutOptions{Concurrency: 2})
+	assert.NilError(t, err)
+	assert.Equal(t, 5, len(res.Results))
+	assert.Equal(t, float64(3), *res.Results["ccc"][0].Data)
+	assert.Equal(t, float64(1), *res.Results["a/s"][0].Data)
+	assert.Equal(t, 1, len(res.Errors))
+	assert.ErrorContains(t, res.Errors["broken"], "500")
+	assert.Assert(t, atomic.LoadInt32(&maxInFlight) <= 2)
+
+	_, err = client.Forecast.GetAggregatedDataForUnits(context.Background(), "v", units, &RequestRangeOptions{}, nil)
+	assert.Equal(t, ErrorReqfieldsMissing, err)
+}
+
+func TestFanOutRetriesRateLimited(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var mu sync.Mutex
+	limited := map[string]bool{}
+	mux.HandleFunc("/forecasts/forecast-variables/v/calculated-forecast", func(w http.ResponseWriter, r *http.Request) {
+		unit := r.URL.Query().Get("externalUnitId")
+		mu.Lock()
+		first := !limited[unit]
+		limited[unit] = true
+		mu.Unlock()
+		if first && unit == "a" {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprintf(w, `[{"externalUnitId":%q}]`, unit)
+	})
+
+	res, err := client.Forecast.GetCalculatedForecastForUnits(context.Background(), "v", testUnits("a", "b"), testRangeTemplate(), &FanOutOptions{Concurrency: 1})
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(res.Errors))
+	assert.Equal(t, "a", *res.Results["a"][0].ExternalUnitID)
+	assert.Equal(t, "b", *res.Results["b"][0].ExternalUnitID)
+}
+
+func TestFanOutPartialResultsOnCancel(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mux.HandleFunc("/forecasts/forecast-variables/v/forecast-data", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("externalUnitId") == "b" {
+			cancel()
+			time.Sleep(50 * time.Millisecond)
+		}
+		fmt.Fprint(w, `[{"externalForecastVariableId":"v"}]`)
+	})
+
+	res, err := client.Forecast.GetForecastDataForUnits(ctx, "v", testUnits("a", "b", "c"), testRangeTemplate(), &FanOutOptions{Concurrency: 1})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, len(res.Results["a"]))
+	assert.Equal(t, context.Canceled, res.Errors["b"])
+	assert.Equal(t, context.Canceled, res.Errors["c"])
+}
@@ -122,20 +122,30 @@ type EditCalculatedRequest struct {
 	WeekPattern            int32     `json:"weekPattern"`
 }
 
-// Weekday defines a weekday
+// Weekday defines a weekday. Values Quinyx adds after this library are kept as
+// they are, see IsKnown.
 type Weekday string
 
-// TagTypes
+// Weekdays
 const (
 	Monday    Weekday = "0"
-	Tuesday           = "1"
-	Wednesday         = "2"
-	Thursday          = "3"
-	Friday            = "4"
-	Saturday          = "5"
-	Sunday            = "6"
+	Tuesday   Weekday = "1"
+	Wednesday Weekday = "2"
+	Thursday  Weekday = "3"
+	Friday    Weekday = "4"
+	Saturday  Weekday = "5"
+	Sunday    Weekday = "6"
 )
 
+// IsKnown reports whether the Weekday is one of the Weekdays
+func (wd Weekday) IsKnown() bool {
+	switch wd {
+	case Monday, Tuesday, Wednesday, Thursday, Friday, Saturday, Sunday:
+		return true
+	}
+	return false
+}
+
 // DynamicRule defines a dynamic rule
 type DynamicRule struct {
 	Amount                     int64       `json:"amount"`
@@ -357,6 +367,9 @@ func (s *ForecastService) UploadBudgetData(ctx context.Context, appendData bool,
 			return nil, fmt.Errorf("The total amount of data rows must not exceed 366 in a single call")
 		}
 	}
+	if err := s.client.UploadValidator.checkDataProviderInputs(dil); err != nil {
+		return nil, err
+	}
 	req, err := s.client.NewRequest("POST", u, dil)
 	if err != nil {
 		return nil, err
@@ -380,6 +393,22 @@ func (g *RequestRangeOptions) dayDistance() float64 {
 	return math.Floor(g.EndTime.Sub(g.StartTime).Hours() / 24)
 }
 
+// chunks splits the range into consecutive ranges no wider than maxDaysRange
+func (g *RequestRangeOptions) chunks() []*RequestRangeOptions {
+	var c []*RequestRangeOptions
+	for start := g.StartTime; start.Before(g.EndTime); {
+		end := start.AddDate(0, 0, maxDaysRange)
+		if end.After(g.EndTime) {
+			end = g.EndTime
+		}
+		chunk := *g
+		chunk.StartTime, chunk.EndTime = start, end
+		c = append(c, &chunk)
+		start = end
+	}
+	return c
+}
+
 func (g *RequestOptions) hasRequiredFields() bool {
 	if g == nil {
 		return false
@@ -556,6 +585,9 @@ func (s *ForecastService) UploadPredictedData(ctx context.Context, inlist *Predi
 			return nil, fmt.Errorf("The total amount of data rows must not exceed 366 in a single call")
 		}
 	}
+	if err := s.client.UploadValidator.checkPredictions(inlist); err != nil {
+		return nil, err
+	}
 	req, err := s.client.NewRequest("POST", u, inlist)
 	if err != nil {
 		return nil, err
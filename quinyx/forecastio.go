@@ -0,0 +1,586 @@
+package quinyx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeFormatUnix is a ForecastCSVOptions.TimeFormat for timestamps in seconds since epoch
+const TimeFormatUnix = "unix"
+
+// ForecastColumns maps CSV header names to forecast fields
+type ForecastColumns struct {
+	Unit      string
+	Section   string // optional, rows without section have a nil ExternalSectionID
+	Variable  string
+	Timestamp string
+	Value     string
+}
+
+// DefaultForecastColumns are the column names used when none are configured
+var DefaultForecastColumns = ForecastColumns{
+	Unit:      "unit",
+	Section:   "section",
+	Variable:  "variable",
+	Timestamp: "timestamp",
+	Value:     "value",
+}
+
+// ForecastCSVOptions configures how forecast CSV files are parsed
+type ForecastCSVOptions struct {
+	// Columns defaults to DefaultForecastColumns
+	Columns *ForecastColumns
+	// TimeFormat is a time layout or TimeFormatUnix, defaults to time.RFC3339
+	TimeFormat string
+	// Location is used for timestamps without a zone, defaults to UTC
+	Location *time.Location
+	// Comma is the field delimiter, defaults to ','
+	Comma rune
+}
+
+// ForecastRow is a single value of a forecast variable in a unit and section
+type ForecastRow struct {
+	ExternalForecastVariableID string
+	ExternalUnitID             string
+	ExternalSectionID          *string
+	Payload                    *Payload
+}
+
+// ForecastRowIterator is a source of forecast rows, Next returns io.EOF when exhausted
+type ForecastRowIterator interface {
+	Next() (*ForecastRow, error)
+}
+
+// ParseError is an error in a single line of an input file. File is empty when the
+// input has no name.
+type ParseError struct {
+	File   string
+	Line   int
+	Column string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	pos := fmt.Sprintf("%s:%d", e.File, e.Line)
+	if e.File == "" {
+		pos = fmt.Sprintf("line %d", e.Line)
+	}
+	if e.Column != "" {
+		return fmt.Sprintf("%s: column %q: %v", pos, e.Column, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", pos, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// lineReader hands a csv.Reader at most one line per Read, so the lines it has
+// counted are the lines the csv.Reader has consumed
+type lineReader struct {
+	r     *bufio.Reader
+	rest  []byte
+	err   error
+	lines int
+	last  byte
+}
+
+// newCSVReader returns a csv.Reader of r and the lineReader that tracks its lines
+func newCSVReader(r io.Reader) (*csv.Reader, *lineReader) {
+	lr := &lineReader{r: bufio.NewReader(r)}
+	return csv.NewReader(lr), lr
+}
+
+func (lr *lineReader) Read(p []byte) (int, error) {
+	if len(lr.rest) == 0 {
+		if lr.err != nil {
+			return 0, lr.err
+		}
+		line, err := lr.r.ReadSlice('\n')
+		if err != nil && err != bufio.ErrBufferFull {
+			lr.err = err
+		}
+		if len(line) == 0 {
+			return 0, lr.err
+		}
+		lr.rest = line
+	}
+	n := copy(p, lr.rest)
+	lr.rest = lr.rest[n:]
+	if n > 0 {
+		lr.last = p[n-1]
+		if lr.last == '\n' {
+			lr.lines++
+		}
+	}
+	return n, nil
+}
+
+// recordLine returns the line rec, the record just read, starts on. A quoted
+// field can span several lines, so it is not the number of records read.
+func (lr *lineReader) recordLine(rec []string) int {
+	end := lr.lines
+	if lr.last != '\n' {
+		end++ // the last line of the file has no line break
+	}
+	for _, f := range rec {
+		end -= strings.Count(f, "\n")
+	}
+	return end
+}
+
+// ForecastCSVReader streams ForecastRows from a CSV file with a header row
+type ForecastCSVReader struct {
+	forecastRowParser
+	file  string
+	r     *csv.Reader
+	lines *lineReader
+	index map[string]int
+	line  int
+}
+
+// NewForecastCSVReader returns a reader of r, file is only used in errors
+func NewForecastCSVReader(r io.Reader, file string, opts *ForecastCSVOptions) *ForecastCSVReader {
+	fr := &ForecastCSVReader{forecastRowParser: newForecastRowParser(opts), file: file}
+	fr.r, fr.lines = newCSVReader(r)
+	if fr.opts.Comma != 0 {
+		fr.r.Comma = fr.opts.Comma
+	}
+	fr.r.TrimLeadingSpace = true
+	fr.r.ReuseRecord = true
+	return fr
+}
+
+// Next returns the next row, or io.EOF at the end of the file.
+// Errors in the file are returned as *ParseError.
+func (fr *ForecastCSVReader) Next() (*ForecastRow, error) {
+	if fr.index == nil {
+		if err := fr.readHeader(); err != nil {
+			return nil, err
+		}
+	}
+	rec, err := fr.r.Read()
+	if err == io.EOF {
+		return nil, err
+	}
+	if err != nil {
+		if pe, ok := err.(*csv.ParseError); ok {
+			return nil, &ParseError{File: fr.file, Line: pe.Line, Err: pe.Err}
+		}
+		return nil, &ParseError{File: fr.file, Line: fr.line + 1, Err: err}
+	}
+	fr.line = fr.lines.recordLine(rec)
+	row, column, err := fr.parseRow(func(column string) (string, error) {
+		return fr.field(rec, column), nil
+	})
+	if err != nil {
+		return nil, &ParseError{File: fr.file, Line: fr.line, Column: column, Err: err}
+	}
+	return row, nil
+}
+
+func (fr *ForecastCSVReader) readHeader() error {
+	header, err := fr.r.Read()
+	if err == io.EOF {
+		return &ParseError{File: fr.file, Line: 1, Err: fmt.Errorf("header row missing")}
+	}
+	if err != nil {
+		return &ParseError{File: fr.file, Line: 1, Err: err}
+	}
+	fr.line = fr.lines.recordLine(header)
+	fr.index = make(map[string]int, len(header))
+	for i, h := range header {
+		fr.index[strings.TrimSpace(h)] = i
+	}
+	for _, c := range []string{fr.columns.Unit, fr.columns.Variable, fr.columns.Timestamp, fr.columns.Value} {
+		if _, ok := fr.index[c]; !ok {
+			return &ParseError{File: fr.file, Line: fr.line, Column: c, Err: fmt.Errorf("column missing in header")}
+		}
+	}
+	return nil
+}
+
+func (fr *ForecastCSVReader) field(rec []string, column string) string {
+	i, ok := fr.index[column]
+	if !ok || i >= len(rec) {
+		return ""
+	}
+	return strings.TrimSpace(rec[i])
+}
+
+// ForecastJSONLReader streams ForecastRows from a JSON Lines file, one object per line
+// keyed by the column names. Values are strings or numbers, null or a missing key is empty.
+type ForecastJSONLReader struct {
+	forecastRowParser
+	file string
+	r    *bufio.Reader
+	line int
+}
+
+// NewForecastJSONLReader returns a reader of r, file is only used in errors.
+// opts.Comma is not used.
+func NewForecastJSONLReader(r io.Reader, file string, opts *ForecastCSVOptions) *ForecastJSONLReader {
+	return &ForecastJSONLReader{forecastRowParser: newForecastRowParser(opts), file: file, r: bufio.NewReader(r)}
+}
+
+// Next returns the next row, or io.EOF at the end of the file. Blank lines are skipped.
+// Errors in the file are returned as *ParseError.
+func (jr *ForecastJSONLReader) Next() (*ForecastRow, error) {
+	for {
+		b, err := jr.r.ReadBytes('\n')
+		if len(b) == 0 && err != nil {
+			if err == io.EOF {
+				return nil, err
+			}
+			return nil, &ParseError{File: jr.file, Line: jr.line + 1, Err: err}
+		}
+		jr.line++
+		b = bytes.TrimSpace(b)
+		if len(b) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		var obj map[string]interface{}
+		if err := dec.Decode(&obj); err != nil {
+			return nil, &ParseError{File: jr.file, Line: jr.line, Err: err}
+		}
+		if dec.More() {
+			return nil, &ParseError{File: jr.file, Line: jr.line, Err: fmt.Errorf("more than one value on the line")}
+		}
+		row, column, err := jr.parseRow(func(column string) (string, error) {
+			switch v := obj[column].(type) {
+			case nil:
+				return "", nil
+			case string:
+				return strings.TrimSpace(v), nil
+			case json.Number:
+				return v.String(), nil
+			default:
+				return "", fmt.Errorf("%T is not a string or number", v)
+			}
+		})
+		if err != nil {
+			return nil, &ParseError{File: jr.file, Line: jr.line, Column: column, Err: err}
+		}
+		return row, nil
+	}
+}
+
+// forecastRowParser builds ForecastRows from the values of a record by column name
+type forecastRowParser struct {
+	opts    ForecastCSVOptions
+	columns ForecastColumns
+}
+
+func newForecastRowParser(opts *ForecastCSVOptions) forecastRowParser {
+	var p forecastRowParser
+	if opts != nil {
+		p.opts = *opts
+	}
+	p.columns = DefaultForecastColumns
+	if p.opts.Columns != nil {
+		p.columns = *p.opts.Columns
+	}
+	if p.opts.TimeFormat == "" {
+		p.opts.TimeFormat = time.RFC3339
+	}
+	if p.opts.Location == nil {
+		p.opts.Location = time.UTC
+	}
+	return p
+}
+
+// parseRow returns the row of the values returned by field, or the column of the error
+func (p *forecastRowParser) parseRow(field func(column string) (string, error)) (*ForecastRow, string, error) {
+	values := map[string]string{}
+	for _, c := range []string{p.columns.Variable, p.columns.Unit, p.columns.Section, p.columns.Timestamp, p.columns.Value} {
+		if c == "" {
+			continue
+		}
+		v, err := field(c)
+		if err != nil {
+			return nil, c, err
+		}
+		values[c] = v
+	}
+
+	row := &ForecastRow{
+		ExternalForecastVariableID: values[p.columns.Variable],
+		ExternalUnitID:             values[p.columns.Unit],
+		Payload:                    &Payload{},
+	}
+	if row.ExternalForecastVariableID == "" {
+		return nil, p.columns.Variable, fmt.Errorf("value missing")
+	}
+	if row.ExternalUnitID == "" {
+		return nil, p.columns.Unit, fmt.Errorf("value missing")
+	}
+	if s := values[p.columns.Section]; s != "" {
+		row.ExternalSectionID = String(s)
+	}
+
+	ts, err := p.parseTime(values[p.columns.Timestamp])
+	if err != nil {
+		return nil, p.columns.Timestamp, err
+	}
+	row.Payload.Timestamp = &Timestamp{ts}
+
+	if v := values[p.columns.Value]; v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, p.columns.Value, err
+		}
+		row.Payload.Data = Float64(f)
+	}
+	return row, "", nil
+}
+
+func (p *forecastRowParser) parseTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, fmt.Errorf("value missing")
+	}
+	if p.opts.TimeFormat == TimeFormatUnix {
+		sec, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(sec, 0).In(p.opts.Location), nil
+	}
+	return time.ParseInLocation(p.opts.TimeFormat, v, p.opts.Location)
+}
+
+type forecastGroupKey struct {
+	variable, unit, section string
+	hasSection              bool
+}
+
+func (row *ForecastRow) groupKey() forecastGroupKey {
+	k := forecastGroupKey{variable: row.ExternalForecastVariableID, unit: row.ExternalUnitID}
+	if row.ExternalSectionID != nil {
+		k.section, k.hasSection = *row.ExternalSectionID, true
+	}
+	return k
+}
+
+// ReadDataProviderInputs drains it into one DataProviderInput per variable, unit and section,
+// in the order they first appear.
+func ReadDataProviderInputs(it ForecastRowIterator) (*DataProviderInputList, error) {
+	list := &DataProviderInputList{}
+	groups := map[forecastGroupKey]int{}
+	for {
+		row, err := it.Next()
+		if err == io.EOF {
+			return list, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		k := row.groupKey()
+		i, ok := groups[k]
+		if !ok {
+			i = len(list.DataProviderInputs)
+			groups[k] = i
+			list.DataProviderInputs = append(list.DataProviderInputs, DataProviderInput{
+				ExternalForecastVariableID: String(row.ExternalForecastVariableID),
+				ExternalUnitID:             String(row.ExternalUnitID),
+				ExternalSectionID:          row.ExternalSectionID,
+			})
+		}
+		list.DataProviderInputs[i].DataPayload = append(list.DataProviderInputs[i].DataPayload, row.Payload)
+	}
+}
+
+// ReadForecastPredictions drains it into one ForecastPrediction per variable, unit and section,
+// in the order they first appear. The configuration and run fields are copied from template.
+func ReadForecastPredictions(it ForecastRowIterator, template *ForecastPrediction) (*PredictedDataInputList, error) {
+	list := &PredictedDataInputList{}
+	groups := map[forecastGroupKey]int{}
+	for {
+		row, err := it.Next()
+		if err == io.EOF {
+			return list, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		k := row.groupKey()
+		i, ok := groups[k]
+		if !ok {
+			fp := ForecastPrediction{}
+			if template != nil {
+				fp.ExternalForecastConfigurationID = template.ExternalForecastConfigurationID
+				fp.RunIdentifier = template.RunIdentifier
+				fp.RunTimestamp = template.RunTimestamp
+			}
+			fp.ExternalForecastVariableID = String(row.ExternalForecastVariableID)
+			fp.ExternalUnitID = String(row.ExternalUnitID)
+			fp.ExternalSectionID = row.ExternalSectionID
+			i = len(list.ForecastPredictions)
+			groups[k] = i
+			list.ForecastPredictions = append(list.ForecastPredictions, fp)
+		}
+		list.ForecastPredictions[i].Payloads = append(list.ForecastPredictions[i].Payloads, row.Payload)
+	}
+}
+
+// ExportFormat is the file format written by a ForecastWriter
+type ExportFormat int
+
+// ExportFormats
+const (
+	ExportCSV ExportFormat = iota
+	ExportJSONL
+)
+
+var (
+	dataProviderHeader       = []string{"unit", "section", "variable", "timestamp", "value"}
+	calculatedForecastHeader = []string{"unit", "section", "variable", "configuration", "startTime", "endTime", "value", "editedValue"}
+)
+
+// ForecastWriter exports forecast data as CSV or JSON Lines. A single writer
+// holds either DataProviders or CalculatedForecasts, as they have different columns.
+type ForecastWriter struct {
+	// Location timestamps are written in, defaults to the location they were decoded with
+	Location *time.Location
+
+	w      io.Writer
+	format ExportFormat
+	csv    *csv.Writer
+	enc    *json.Encoder
+	header []string
+}
+
+// NewForecastWriter returns a writer of format to w, Flush must be called when done
+func NewForecastWriter(w io.Writer, format ExportFormat) *ForecastWriter {
+	fw := &ForecastWriter{w: w, format: format}
+	if format == ExportJSONL {
+		fw.enc = json.NewEncoder(w)
+		fw.enc.SetEscapeHTML(false)
+	} else {
+		fw.csv = csv.NewWriter(w)
+	}
+	return fw
+}
+
+// WriteDataProviders writes the results of GetActualDataStream or GetForecastData
+func (fw *ForecastWriter) WriteDataProviders(dps []*DataProvider) error {
+	if err := fw.start(dataProviderHeader); err != nil {
+		return err
+	}
+	for _, dp := range dps {
+		for _, p := range dp.DataPayload {
+			rec := []string{
+				stringValue(dp.ExternalUnitID),
+				stringValue(dp.ExternalSectionID),
+				stringValue(dp.ExternalForecastVariableID),
+				fw.formatTime(p.Timestamp),
+				formatFloat(p.Data),
+			}
+			if err := fw.write(rec, p.Data, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteCalculatedForecasts writes the results of GetCalculatedForecast for externalForecastVariableID
+func (fw *ForecastWriter) WriteCalculatedForecasts(externalForecastVariableID string, cfs []*CalculatedForecast) error {
+	if err := fw.start(calculatedForecastHeader); err != nil {
+		return err
+	}
+	for _, cf := range cfs {
+		for _, p := range cf.DataPayload {
+			rec := []string{
+				stringValue(cf.ExternalUnitID),
+				stringValue(cf.ExternalSectionID),
+				externalForecastVariableID,
+				stringValue(cf.ExternalForecastConfigurationID),
+				fw.formatTime(p.StartTime),
+				fw.formatTime(p.EndTime),
+				formatFloat(p.Data),
+				formatFloat(p.EditedData),
+			}
+			if err := fw.write(rec, p.Data, p.EditedData); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Flush writes any buffered data to the underlying writer
+func (fw *ForecastWriter) Flush() error {
+	if fw.csv != nil {
+		fw.csv.Flush()
+		return fw.csv.Error()
+	}
+	return nil
+}
+
+func (fw *ForecastWriter) start(header []string) error {
+	if fw.header != nil {
+		if fw.header[len(fw.header)-1] != header[len(header)-1] {
+			return fmt.Errorf("ForecastWriter cannot mix data providers and calculated forecasts")
+		}
+		return nil
+	}
+	fw.header = header
+	if fw.csv != nil {
+		return fw.csv.Write(header)
+	}
+	return nil
+}
+
+// write writes rec as a CSV record, or as a JSON object keyed by the header with numeric values
+func (fw *ForecastWriter) write(rec []string, value, editedValue *float64) error {
+	if fw.csv != nil {
+		return fw.csv.Write(rec)
+	}
+	obj := make(map[string]interface{}, len(rec))
+	for i, h := range fw.header {
+		if rec[i] != "" {
+			obj[h] = rec[i]
+		}
+	}
+	if value != nil {
+		obj["value"] = *value
+	}
+	if editedValue != nil {
+		obj["editedValue"] = *editedValue
+	}
+	return fw.enc.Encode(obj)
+}
+
+func (fw *ForecastWriter) formatTime(ts *Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+	t := ts.Time
+	if fw.Location != nil {
+		t = t.In(fw.Location)
+	}
+	return t.Format(time.RFC3339)
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatFloat(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', -1, 64)
+}
@@ -0,0 +1,147 @@
+package quinyx
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestReadDataProviderInputs(t *testing.T) {
+	in := "store;dept;kpi;when;amount\n" +
+		"s1;;sales;2020-10-12 08:00;10.5\n" +
+		"s1;d1;sales;2020-10-12 08:00;1\n" +
+		"s1;;sales;2020-10-12 08:15;\n"
+	loc, err := time.LoadLocation("Europe/Stockholm")
+	assert.NilError(t, err)
+	r := NewForecastCSVReader(strings.NewReader(in), "pos.csv", &ForecastCSVOptions{
+		Columns:    &ForecastColumns{Unit: "store", Section: "dept", Variable: "kpi", Timestamp: "when", Value: "amount"},
+		TimeFormat: "2006-01-02 15:04",
+		Location:   loc,
+		Comma:      ';',
+	})
+	list, err := ReadDataProviderInputs(r)
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(list.DataProviderInputs))
+
+	first := list.DataProviderInputs[0]
+	assert.Equal(t, "s1", *first.ExternalUnitID)
+	assert.Assert(t, first.ExternalSectionID == nil)
+	assert.Equal(t, 2, len(first.DataPayload))
+	assert.Equal(t, 10.5, *first.DataPayload[0].Data)
+	assert.Assert(t, first.DataPayload[1].Data == nil)
+	assert.Assert(t, first.DataPayload[0].Timestamp.Equal(Timestamp{time.Date(2020, time.October, 12, 6, 0, 0, 0, time.UTC)}))
+	assert.Equal(t, "d1", *list.DataProviderInputs[1].ExternalSectionID)
+}
+
+func TestReadForecastPredictions(t *testing.T) {
+	in := "unit,variable,timestamp,value\nu,v,1602489600,3\nu,v,1602490500,4\n"
+	r := NewForecastCSVReader(strings.NewReader(in), "run.csv", &ForecastCSVOptions{TimeFormat: TimeFormatUnix})
+	list, err := ReadForecastPredictions(r, &ForecastPrediction{
+		ExternalForecastConfigurationID: String("cfg"),
+		RunIdentifier:                   String("run-1"),
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(list.ForecastPredictions))
+	assert.Equal(t, "cfg", *list.ForecastPredictions[0].ExternalForecastConfigurationID)
+	assert.Equal(t, "run-1", *list.ForecastPredictions[0].RunIdentifier)
+	assert.Equal(t, 2, len(list.ForecastPredictions[0].Payloads))
+}
+
+func TestForecastCSVReaderErrors(t *testing.T) {
+	_, err := ReadDataProviderInputs(NewForecastCSVReader(strings.NewReader("unit,variable,timestamp,value\nu,v,2020-10-12T08:00:00Z,1\nu,v,yesterday,1\n"), "bad.csv", nil))
+	var pe *ParseError
+	assert.Assert(t, errors.As(err, &pe))
+	assert.Equal(t, 3, pe.Line)
+	assert.Equal(t, "timestamp", pe.Column)
+	assert.ErrorContains(t, err, `bad.csv:3: column "timestamp"`)
+
+	_, err = ReadDataProviderInputs(NewForecastCSVReader(strings.NewReader("unit,timestamp,value\n"), "bad.csv", nil))
+	assert.ErrorContains(t, err, `bad.csv:1: column "variable": column missing in header`)
+
+	_, err = ReadDataProviderInputs(NewForecastCSVReader(strings.NewReader("unit,variable,timestamp,value\n,v,2020-10-12T08:00:00Z,1\n"), "bad.csv", nil))
+	assert.ErrorContains(t, err, `bad.csv:2: column "unit": value missing`)
+
+	// The quoted section spans two lines, so the bad timestamp is on line 4
+	in := "unit,variable,timestamp,value,section\nu,v,2020-10-12T08:00:00Z,1,\"a\nb\"\nu,v,yesterday,1,\n"
+	_, err = ReadDataProviderInputs(NewForecastCSVReader(strings.NewReader(in), "bad.csv", nil))
+	assert.ErrorContains(t, err, `bad.csv:4: column "timestamp"`)
+
+	// Blank lines are skipped and the last line has no line break
+	_, err = ReadDataProviderInputs(NewForecastCSVReader(strings.NewReader("unit,variable,timestamp,value\r\n\r\nu,v,yesterday,1"), "bad.csv", nil))
+	assert.ErrorContains(t, err, `bad.csv:3: column "timestamp"`)
+}
+
+func TestForecastJSONLReader(t *testing.T) {
+	in := `{"unit":"u","variable":"v","timestamp":1602489600,"value":3}
+
+{"unit":"u","section":null,"variable":"v","timestamp":"1602490500","value":10000000}
+{"unit":"u","section":"d","variable":"v","timestamp":1602490500}
+`
+	list, err := ReadDataProviderInputs(NewForecastJSONLReader(strings.NewReader(in), "run.jsonl", &ForecastCSVOptions{TimeFormat: TimeFormatUnix}))
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(list.DataProviderInputs))
+	first := list.DataProviderInputs[0]
+	assert.Assert(t, first.ExternalSectionID == nil)
+	assert.Equal(t, 2, len(first.DataPayload))
+	assert.Equal(t, float64(1e7), *first.DataPayload[1].Data)
+	assert.Assert(t, first.DataPayload[1].Timestamp.Equal(Timestamp{time.Unix(1602490500, 0)}))
+	assert.Equal(t, "d", *list.DataProviderInputs[1].ExternalSectionID)
+	assert.Assert(t, list.DataProviderInputs[1].DataPayload[0].Data == nil)
+
+	var pe *ParseError
+	_, err = ReadDataProviderInputs(NewForecastJSONLReader(strings.NewReader("\n{\"unit\":\"u\",\"variable\":\"v\",\"timestamp\":\"yesterday\"}\n"), "bad.jsonl", nil))
+	assert.Assert(t, errors.As(err, &pe))
+	assert.Equal(t, 2, pe.Line)
+	assert.ErrorContains(t, err, `bad.jsonl:2: column "timestamp"`)
+
+	_, err = ReadDataProviderInputs(NewForecastJSONLReader(strings.NewReader(`{"unit":"u","variable":true}`), "bad.jsonl", nil))
+	assert.Error(t, err, `bad.jsonl:1: column "variable": bool is not a string or number`)
+
+	_, err = ReadDataProviderInputs(NewForecastJSONLReader(strings.NewReader("{\"unit\":\"u\"}\n{\"unit\":"), "bad.jsonl", nil))
+	assert.ErrorContains(t, err, `bad.jsonl:1: column "variable": value missing`)
+	_, err = ReadDataProviderInputs(NewForecastJSONLReader(strings.NewReader("{\"unit\":"), "bad.jsonl", nil))
+	assert.ErrorContains(t, err, `bad.jsonl:1: unexpected EOF`)
+}
+
+func TestForecastWriter(t *testing.T) {
+	ts := &Timestamp{time.Date(2020, time.October, 12, 8, 0, 0, 0, time.UTC)}
+	dps := []*DataProvider{{
+		ExternalForecastVariableID: String("v"),
+		ExternalUnitID:             String("u"),
+		DataPayload:                []*Payload{{Data: Float64(1.5), Timestamp: ts}},
+	}}
+
+	var buf bytes.Buffer
+	w := NewForecastWriter(&buf, ExportCSV)
+	assert.NilError(t, w.WriteDataProviders(dps))
+	assert.NilError(t, w.Flush())
+	assert.Equal(t, "unit,section,variable,timestamp,value\nu,,v,2020-10-12T08:00:00Z,1.5\n", buf.String())
+
+	// CSV output can be read back
+	list, err := ReadDataProviderInputs(NewForecastCSVReader(&buf, "export.csv", nil))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, dps[0].DataPayload, list.DataProviderInputs[0].DataPayload)
+
+	buf.Reset()
+	w = NewForecastWriter(&buf, ExportJSONL)
+	assert.NilError(t, w.WriteCalculatedForecasts("v", []*CalculatedForecast{{
+		ExternalForecastConfigurationID: String("cfg"),
+		ExternalUnitID:                  String("u"),
+		DataPayload:                     []*CalculatedPayload{{Data: Float64(2), EditedData: Float64(3), StartTime: ts, EndTime: ts}},
+	}}))
+	assert.NilError(t, w.Flush())
+	assert.Equal(t, `{"configuration":"cfg","editedValue":3,"endTime":"2020-10-12T08:00:00Z","startTime":"2020-10-12T08:00:00Z","unit":"u","value":2,"variable":"v"}`+"\n", buf.String())
+	assert.ErrorContains(t, w.WriteDataProviders(dps), "cannot mix")
+
+	// JSONL output can be read back
+	buf.Reset()
+	w = NewForecastWriter(&buf, ExportJSONL)
+	assert.NilError(t, w.WriteDataProviders(dps))
+	list, err = ReadDataProviderInputs(NewForecastJSONLReader(&buf, "export.jsonl", nil))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, dps[0].DataPayload, list.DataProviderInputs[0].DataPayload)
+}
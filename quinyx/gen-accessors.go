@@ -0,0 +1,257 @@
+//go:build ignore
+// +build ignore
+
+// gen-accessors generates accessor methods for the pointer fields of the models.
+//
+// A model is an exported struct with json or url tags. Run go generate after
+// changing a model, TestAccessors fails when a pointer field has no accessor.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+const fileSuffix = "-accessors.go"
+
+var (
+	verbose = flag.Bool("v", false, "Print verbose log messages")
+
+	sourceTmpl = template.Must(template.New("source").Parse(source))
+
+	// valueTypes are returned by value, other pointers are returned as they are
+	valueTypes = map[string]bool{
+		"bool": true, "string": true, "Timestamp": true,
+		"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+		"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+		"float32": true, "float64": true,
+	}
+)
+
+func logf(fmt string, args ...interface{}) {
+	if *verbose {
+		log.Printf(fmt, args...)
+	}
+}
+
+func main() {
+	flag.Parse()
+	fset := token.NewFileSet()
+
+	pkgs, err := parser.ParseDir(fset, ".", sourceFilter, 0)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	for pkgName, pkg := range pkgs {
+		t := &templateData{
+			filename: pkgName + fileSuffix,
+			Package:  pkgName,
+			Imports:  map[string]bool{},
+		}
+		for filename, f := range pkg.Files {
+			logf("Processing %v...", filename)
+			t.processAST(f)
+		}
+		if err := t.dump(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	logf("Done.")
+}
+
+func sourceFilter(fi os.FileInfo) bool {
+	return !strings.HasSuffix(fi.Name(), "_test.go") && !strings.HasSuffix(fi.Name(), fileSuffix) && fi.Name() != "gen-accessors.go"
+}
+
+// isModel reports whether the struct is sent to or received from Quinyx,
+// which is when any of its fields has a json or url tag
+func isModel(st *ast.StructType) bool {
+	for _, field := range st.Fields.List {
+		if field.Tag == nil {
+			continue
+		}
+		tag := reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+		if _, ok := tag.Lookup("json"); ok {
+			return true
+		}
+		if _, ok := tag.Lookup("url"); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *templateData) processAST(f *ast.File) {
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			ts, ok := spec.(*ast.TypeSpec)
+			if !ok || !ts.Name.IsExported() {
+				continue
+			}
+			st, ok := ts.Type.(*ast.StructType)
+			if !ok || !isModel(st) {
+				continue
+			}
+			for _, field := range st.Fields.List {
+				se, ok := field.Type.(*ast.StarExpr)
+				if !ok {
+					continue
+				}
+				for _, name := range field.Names {
+					if !name.IsExported() {
+						continue
+					}
+					switch x := se.X.(type) {
+					case *ast.Ident:
+						t.addIdent(x, ts.Name.String(), name.String())
+					case *ast.SelectorExpr:
+						t.addSelectorExpr(x, ts.Name.String(), name.String())
+					default:
+						logf("processAST: type %q, field %q, unknown %T: %+v", ts.Name, name, x, x)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (t *templateData) addIdent(x *ast.Ident, receiverType, fieldName string) {
+	if valueTypes[x.String()] {
+		zeroValue := "0"
+		switch x.String() {
+		case "bool":
+			zeroValue = "false"
+		case "string":
+			zeroValue = `""`
+		case "Timestamp":
+			zeroValue = "Timestamp{}"
+		}
+		t.Getters = append(t.Getters, newGetter(receiverType, fieldName, x.String(), zeroValue, true))
+		return
+	}
+	t.Getters = append(t.Getters, newGetter(receiverType, fieldName, "*"+x.String(), "nil", false))
+}
+
+func (t *templateData) addSelectorExpr(x *ast.SelectorExpr, receiverType, fieldName string) {
+	xX, ok := x.X.(*ast.Ident)
+	if !ok {
+		return
+	}
+	fieldType := fmt.Sprintf("*%v.%v", xX, x.Sel)
+	t.Imports[packagePath(xX.String())] = true
+	t.Getters = append(t.Getters, newGetter(receiverType, fieldName, fieldType, "nil", false))
+}
+
+func packagePath(pkg string) string {
+	switch pkg {
+	case "url":
+		return "net/url"
+	case "http":
+		return "net/http"
+	}
+	return pkg
+}
+
+type templateData struct {
+	filename string
+	Package  string
+	Imports  map[string]bool
+	Getters  []*getter
+}
+
+type getter struct {
+	sortVal      string // Lower-case version of "ReceiverType.FieldName".
+	ReceiverVar  string // The one-letter variable name to match the ReceiverType.
+	ReceiverType string
+	FieldName    string
+	FieldType    string
+	ZeroValue    string
+	Deref        bool // Getter returns the value the field points to.
+}
+
+func newGetter(receiverType, fieldName, fieldType, zeroValue string, deref bool) *getter {
+	return &getter{
+		sortVal:      strings.ToLower(receiverType) + "." + strings.ToLower(fieldName),
+		ReceiverVar:  strings.ToLower(receiverType[:1]),
+		ReceiverType: receiverType,
+		FieldName:    fieldName,
+		FieldType:    fieldType,
+		ZeroValue:    zeroValue,
+		Deref:        deref,
+	}
+}
+
+func (t *templateData) dump() error {
+	if len(t.Getters) == 0 {
+		logf("No getters for %v; skipping.", t.filename)
+		return nil
+	}
+
+	// Sort getters by ReceiverType.FieldName.
+	sort.Slice(t.Getters, func(i, j int) bool { return t.Getters[i].sortVal < t.Getters[j].sortVal })
+
+	processTemplate := func(tmpl *template.Template, filename string) error {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, t); err != nil {
+			return err
+		}
+		clean, err := format.Source(buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("format.Source:\n%v\n%v", buf.String(), err)
+		}
+
+		logf("Writing %v...", filename)
+		return ioutil.WriteFile(filename, clean, 0644)
+	}
+
+	return processTemplate(sourceTmpl, t.filename)
+}
+
+const source = `// Code generated by gen-accessors; DO NOT EDIT.
+
+package {{.Package}}
+{{with .Imports}}
+import (
+  {{- range $key, $value := .}}
+  "{{$key}}"
+  {{- end}}
+)
+{{end}}
+{{range .Getters}}
+{{if .Deref}}
+// Get{{.FieldName}} returns the {{.FieldName}} field if it's non-nil, zero value otherwise.
+func ({{.ReceiverVar}} *{{.ReceiverType}}) Get{{.FieldName}}() {{.FieldType}} {
+  if {{.ReceiverVar}} == nil || {{.ReceiverVar}}.{{.FieldName}} == nil {
+    return {{.ZeroValue}}
+  }
+  return *{{.ReceiverVar}}.{{.FieldName}}
+}
+{{else}}
+// Get{{.FieldName}} returns the {{.FieldName}} field.
+func ({{.ReceiverVar}} *{{.ReceiverType}}) Get{{.FieldName}}() {{.FieldType}} {
+  if {{.ReceiverVar}} == nil {
+    return {{.ZeroValue}}
+  }
+  return {{.ReceiverVar}}.{{.FieldName}}
+}
+{{end}}
+{{end}}
+`
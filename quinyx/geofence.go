@@ -0,0 +1,154 @@
+package quinyx
+
+import (
+	"fmt"
+	"math"
+)
+
+// earthRadius is the mean radius of the earth in meters
+const earthRadius = 6371008.8
+
+// CoordinateError is the error returned for a geofence with an invalid position or radius
+type CoordinateError struct {
+	// Index of the coordinate in Tag.Coordinates
+	Index  int
+	Field  string
+	Reason string
+}
+
+func (e *CoordinateError) Error() string {
+	return fmt.Sprintf("coordinate %d: %s %s", e.Index, e.Field, e.Reason)
+}
+
+// ValidateCoordinates checks that every geofence has a latitude within [-90, 90],
+// a longitude within [-180, 180] and a positive radius
+func ValidateCoordinates(coordinates []*Coordinate) error {
+	for i, c := range coordinates {
+		switch {
+		case c == nil:
+			return &CoordinateError{Index: i, Field: "coordinate", Reason: "is missing"}
+		case c.Latitude == nil:
+			return &CoordinateError{Index: i, Field: "latitude", Reason: "is missing"}
+		case math.IsNaN(*c.Latitude) || *c.Latitude < -90 || *c.Latitude > 90:
+			return &CoordinateError{Index: i, Field: "latitude", Reason: fmt.Sprintf("%v is outside [-90, 90]", *c.Latitude)}
+		case c.Longitude == nil:
+			return &CoordinateError{Index: i, Field: "longitude", Reason: "is missing"}
+		case math.IsNaN(*c.Longitude) || *c.Longitude < -180 || *c.Longitude > 180:
+			return &CoordinateError{Index: i, Field: "longitude", Reason: fmt.Sprintf("%v is outside [-180, 180]", *c.Longitude)}
+		case c.Radius == nil:
+			return &CoordinateError{Index: i, Field: "radius", Reason: "is missing"}
+		case *c.Radius <= 0:
+			return &CoordinateError{Index: i, Field: "radius", Reason: fmt.Sprintf("%d is not positive", *c.Radius)}
+		}
+	}
+	return nil
+}
+
+// HaversineDistance returns the great-circle distance in meters between two points given in degrees
+func HaversineDistance(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// valid reports whether the geofence has all its fields set
+func (c *Coordinate) valid() bool {
+	return c != nil && c.Latitude != nil && c.Longitude != nil && c.Radius != nil
+}
+
+// DistanceTo returns the distance in meters from the center of the geofence to the point.
+// ok is false when the geofence is missing any of its fields.
+func (c *Coordinate) DistanceTo(latitude, longitude float64) (distance float64, ok bool) {
+	if !c.valid() {
+		return 0, false
+	}
+	return c.distanceTo(latitude, longitude), true
+}
+
+// distanceTo is DistanceTo for a geofence known to be valid
+func (c *Coordinate) distanceTo(latitude, longitude float64) float64 {
+	return HaversineDistance(*c.Latitude, *c.Longitude, latitude, longitude)
+}
+
+// Contains reports whether the point is within the radius of the geofence
+func (c *Coordinate) Contains(latitude, longitude float64) bool {
+	return c.valid() && c.distanceTo(latitude, longitude) <= float64(*c.Radius)
+}
+
+// ContainsPoint reports whether the point lies inside any of the geofences of the tag
+func (t *Tag) ContainsPoint(latitude, longitude float64) bool {
+	for _, c := range t.Coordinates {
+		if c.Contains(latitude, longitude) {
+			return true
+		}
+	}
+	return false
+}
+
+// DistanceTo returns the distance in meters from the point to the edge of the closest
+// geofence of the tag, 0 when the point is inside one. ok is false for tags without geofences.
+func (t *Tag) DistanceTo(latitude, longitude float64) (distance float64, ok bool) {
+	for _, c := range t.Coordinates {
+		if !c.valid() {
+			continue
+		}
+		d := math.Max(0, c.distanceTo(latitude, longitude)-float64(*c.Radius))
+		if !ok || d < distance {
+			distance, ok = d, true
+		}
+	}
+	return distance, ok
+}
+
+// NearestTag returns the tag whose geofences are closest to the point and the distance
+// in meters to the edge of its closest geofence. It returns nil if no tag has a geofence.
+func NearestTag(tags []*Tag, latitude, longitude float64) (*Tag, float64) {
+	var nearest *Tag
+	var best float64
+	for _, t := range tags {
+		if t == nil {
+			continue
+		}
+		if d, ok := t.DistanceTo(latitude, longitude); ok && (nearest == nil || d < best) {
+			nearest, best = t, d
+		}
+	}
+	return nearest, best
+}
+
+// GeofenceOverlap is a pair of overlapping geofences of two different tags
+type GeofenceOverlap struct {
+	A           *Tag
+	ACoordinate *Coordinate
+	B           *Tag
+	BCoordinate *Coordinate
+	// Distance between the centers in meters
+	Distance float64
+}
+
+// FindOverlappingGeofences returns every pair of geofences of different tags, for example
+// of a category, whose circles overlap. Pairs are in the order of tags.
+func FindOverlappingGeofences(tags []*Tag) []*GeofenceOverlap {
+	var overlaps []*GeofenceOverlap
+	for i, a := range tags {
+		for _, b := range tags[i+1:] {
+			if a == nil || b == nil {
+				continue
+			}
+			for _, ca := range a.Coordinates {
+				for _, cb := range b.Coordinates {
+					if !ca.valid() || !cb.valid() {
+						continue
+					}
+					d := ca.distanceTo(*cb.Latitude, *cb.Longitude)
+					if d < float64(*ca.Radius)+float64(*cb.Radius) {
+						overlaps = append(overlaps, &GeofenceOverlap{A: a, ACoordinate: ca, B: b, BCoordinate: cb, Distance: d})
+					}
+				}
+			}
+		}
+	}
+	return overlaps
+}
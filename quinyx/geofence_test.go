@@ -0,0 +1,70 @@
+package quinyx
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func testGeofenceTag(id string, lat, lon float64, radius int32) *Tag {
+	return &Tag{ExternalID: String(id), Coordinates: []*Coordinate{{Latitude: Float64(lat), Longitude: Float64(lon), Radius: Int32(radius)}}}
+}
+
+func TestHaversineDistance(t *testing.T) {
+	// Stockholm Central to Gothenburg Central is about 398 km
+	d := HaversineDistance(59.3303, 18.0582, 57.7089, 11.9733)
+	assert.Assert(t, math.Abs(d-398000) < 2000, "distance %v", d)
+	assert.Equal(t, float64(0), HaversineDistance(1, 2, 1, 2))
+}
+
+func TestGeofences(t *testing.T) {
+	a := testGeofenceTag("a", 59.3303, 18.0582, 500)
+	b := testGeofenceTag("b", 59.3320, 18.0640, 200)
+	c := testGeofenceTag("c", 57.7089, 11.9733, 300)
+	none := &Tag{ExternalID: String("none")}
+
+	assert.Assert(t, a.ContainsPoint(59.3310, 18.0590))
+	assert.Assert(t, !c.ContainsPoint(59.3310, 18.0590))
+	assert.Assert(t, !none.ContainsPoint(59.3310, 18.0590))
+
+	d, ok := a.Coordinates[0].DistanceTo(59.3303, 18.0582)
+	assert.Assert(t, ok)
+	assert.Equal(t, float64(0), d)
+	_, ok = (&Coordinate{Latitude: Float64(1)}).DistanceTo(0, 0)
+	assert.Assert(t, !ok)
+
+	nearest, d := NearestTag([]*Tag{none, c, b}, 59.3303, 18.0582)
+	assert.Equal(t, b, nearest)
+	assert.Assert(t, d > 0 && d < 200, "distance %v", d)
+	nearest, d = NearestTag([]*Tag{a, b}, 59.3303, 18.0582)
+	assert.Equal(t, a, nearest)
+	assert.Equal(t, float64(0), d)
+	nearest, _ = NearestTag([]*Tag{none}, 0, 0)
+	assert.Assert(t, nearest == nil)
+
+	overlaps := FindOverlappingGeofences([]*Tag{a, b, c, none})
+	assert.Equal(t, 1, len(overlaps))
+	assert.Equal(t, a, overlaps[0].A)
+	assert.Equal(t, b, overlaps[0].B)
+}
+
+func TestValidateCoordinates(t *testing.T) {
+	assert.NilError(t, ValidateCoordinates(testGeofenceTag("a", -90, 180, 1).Coordinates))
+
+	var cerr *CoordinateError
+	err := ValidateCoordinates(append(testGeofenceTag("a", 0, 0, 1).Coordinates, &Coordinate{Latitude: Float64(91), Longitude: Float64(0), Radius: Int32(1)}))
+	assert.Assert(t, errors.As(err, &cerr))
+	assert.Equal(t, 1, cerr.Index)
+	assert.Equal(t, "coordinate 1: latitude 91 is outside [-90, 90]", err.Error())
+	assert.ErrorContains(t, ValidateCoordinates(testGeofenceTag("a", 0, -181, 1).Coordinates), "longitude")
+	assert.ErrorContains(t, ValidateCoordinates(testGeofenceTag("a", 0, 0, 0).Coordinates), "radius 0 is not positive")
+	assert.ErrorContains(t, ValidateCoordinates([]*Coordinate{{Latitude: Float64(0), Longitude: Float64(0)}}), "radius is missing")
+
+	client, _, _, teardown := setup()
+	defer teardown()
+	_, _, err = client.Tags.CreateTag(context.Background(), "cc", testGeofenceTag("a", 100, 0, 1))
+	assert.Assert(t, errors.As(err, &cerr))
+}
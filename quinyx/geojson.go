@@ -0,0 +1,232 @@
+package quinyx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GeoJSON property names used for tags
+const (
+	GeoJSONExternalID = "externalId"
+	GeoJSONName       = "name"
+	GeoJSONCode       = "code"
+	GeoJSONRadius     = "radius"
+	// GeoJSONCustomFieldPrefix prefixes the label of each custom field property
+	GeoJSONCustomFieldPrefix = "custom:"
+)
+
+// GeoJSONFeatureCollection is a GeoJSON FeatureCollection of Point features
+type GeoJSONFeatureCollection struct {
+	Type     string            `json:"type"`
+	Features []*GeoJSONFeature `json:"features"`
+}
+
+// GeoJSONFeature is a GeoJSON Feature
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   *GeoJSONGeometry       `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJSONGeometry is a GeoJSON geometry, only Point is used for tags
+type GeoJSONGeometry struct {
+	Type string `json:"type"`
+	// Coordinates are kept raw as their shape depends on Type, see Point
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// NewGeoJSONPoint returns a Point geometry
+func NewGeoJSONPoint(latitude, longitude float64) *GeoJSONGeometry {
+	b, _ := json.Marshal([]float64{longitude, latitude})
+	return &GeoJSONGeometry{Type: "Point", Coordinates: b}
+}
+
+// Point returns the position of a Point geometry
+func (g *GeoJSONGeometry) Point() (latitude, longitude float64, err error) {
+	var pos []float64
+	if g.Type != "Point" {
+		return 0, 0, fmt.Errorf("geometry must be a Point, got %s", g.Type)
+	}
+	if err := json.Unmarshal(g.Coordinates, &pos); err != nil {
+		return 0, 0, err
+	}
+	if len(pos) < 2 {
+		return 0, 0, fmt.Errorf("Point needs a longitude and a latitude")
+	}
+	return pos[1], pos[0], nil
+}
+
+// TagsToGeoJSON converts tags to a FeatureCollection with one Point feature per geofence.
+// Tags without geofences become a feature without geometry so that they are not lost.
+func TagsToGeoJSON(tags []*Tag) *GeoJSONFeatureCollection {
+	fc := &GeoJSONFeatureCollection{Type: "FeatureCollection", Features: []*GeoJSONFeature{}}
+	for _, t := range tags {
+		if t == nil {
+			continue
+		}
+		props := func() map[string]interface{} {
+			p := map[string]interface{}{}
+			if t.ExternalID != nil {
+				p[GeoJSONExternalID] = *t.ExternalID
+			}
+			if t.Name != nil {
+				p[GeoJSONName] = *t.Name
+			}
+			if t.Code != nil {
+				p[GeoJSONCode] = *t.Code
+			}
+			for _, cf := range t.CustomFields {
+				if cf != nil && cf.Label != nil {
+					p[GeoJSONCustomFieldPrefix+*cf.Label] = stringValue(cf.Value)
+				}
+			}
+			return p
+		}
+		n := 0
+		for _, c := range t.Coordinates {
+			if !c.valid() {
+				continue
+			}
+			p := props()
+			p[GeoJSONRadius] = *c.Radius
+			fc.Features = append(fc.Features, &GeoJSONFeature{
+				Type:       "Feature",
+				Geometry:   NewGeoJSONPoint(*c.Latitude, *c.Longitude),
+				Properties: p,
+			})
+			n++
+		}
+		if n == 0 {
+			fc.Features = append(fc.Features, &GeoJSONFeature{Type: "Feature", Properties: props()})
+		}
+	}
+	return fc
+}
+
+// WriteTagsGeoJSON writes tags to w as a FeatureCollection, see TagsToGeoJSON
+func WriteTagsGeoJSON(w io.Writer, tags []*Tag) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc.Encode(TagsToGeoJSON(tags))
+}
+
+// ReadTagsGeoJSON reads a FeatureCollection written by WriteTagsGeoJSON, or by a GIS
+// tool using the same properties. Features with the same externalId are merged into
+// one tag with a geofence per Point, in the order the tags first appear.
+func ReadTagsGeoJSON(r io.Reader) ([]*Tag, error) {
+	var fc GeoJSONFeatureCollection
+	if err := json.NewDecoder(r).Decode(&fc); err != nil {
+		return nil, err
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("GeoJSON type is %q, not FeatureCollection", fc.Type)
+	}
+	var tags []*Tag
+	byID := map[string]*Tag{}
+	for i, f := range fc.Features {
+		if f == nil {
+			continue
+		}
+		id, ok := f.Properties[GeoJSONExternalID].(string)
+		if !ok || id == "" {
+			return nil, fmt.Errorf("feature %d: property %s is missing", i, GeoJSONExternalID)
+		}
+		t, ok := byID[id]
+		if !ok {
+			t = &Tag{ExternalID: String(id)}
+			var err error
+			if t.Name, err = propertyString(f.Properties, GeoJSONName); err != nil {
+				return nil, fmt.Errorf("feature %d: %v", i, err)
+			}
+			if t.Code, err = propertyString(f.Properties, GeoJSONCode); err != nil {
+				return nil, fmt.Errorf("feature %d: %v", i, err)
+			}
+			var labels []string
+			for k := range f.Properties {
+				if strings.HasPrefix(k, GeoJSONCustomFieldPrefix) {
+					labels = append(labels, k)
+				}
+			}
+			sort.Strings(labels)
+			for _, k := range labels {
+				v, err := propertyString(f.Properties, k)
+				if err != nil {
+					return nil, fmt.Errorf("feature %d: %v", i, err)
+				}
+				if v == nil {
+					continue
+				}
+				t.CustomFields = append(t.CustomFields, &CustomField{
+					Label: String(strings.TrimPrefix(k, GeoJSONCustomFieldPrefix)),
+					Value: v,
+				})
+			}
+			byID[id] = t
+			tags = append(tags, t)
+		}
+		if f.Geometry == nil {
+			continue
+		}
+		lat, lon, err := f.Geometry.Point()
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: %v", i, err)
+		}
+		radius, ok := f.Properties[GeoJSONRadius].(float64)
+		if !ok {
+			return nil, fmt.Errorf("feature %d: property %s is missing or not a number", i, GeoJSONRadius)
+		}
+		t.Coordinates = append(t.Coordinates, &Coordinate{
+			Latitude:  Float64(lat),
+			Longitude: Float64(lon),
+			Radius:    Int32(int32(math.Round(radius))),
+		})
+	}
+	for _, t := range tags {
+		if err := ValidateCoordinates(t.Coordinates); err != nil {
+			return nil, fmt.Errorf("tag %s: %v", *t.ExternalID, err)
+		}
+	}
+	return tags, nil
+}
+
+// ExportGeoJSON writes the tags of the category to w as a FeatureCollection
+func (s *TagsService) ExportGeoJSON(ctx context.Context, categoryExternalID string, w io.Writer) error {
+	tags, _, err := s.GetAllTags(ctx, categoryExternalID)
+	if err != nil {
+		return err
+	}
+	return WriteTagsGeoJSON(w, tags)
+}
+
+// ImportGeoJSON creates the tags of the FeatureCollection in r that are not in the
+// category and updates the ones that differ. Tags missing from r are left alone.
+func (s *TagsService) ImportGeoJSON(ctx context.Context, categoryExternalID string, r io.Reader) (*TagImportResult, error) {
+	tags, err := ReadTagsGeoJSON(r)
+	if err != nil {
+		return nil, err
+	}
+	return s.importTags(ctx, categoryExternalID, tags)
+}
+
+// propertyString returns the property k as a string, or nil when it is missing or null.
+// Numbers are written without exponent, so a code such as 10000000 stays as it is.
+func propertyString(properties map[string]interface{}, k string) (*string, error) {
+	switch v := properties[k].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return String(v), nil
+	case float64:
+		return String(strconv.FormatFloat(v, 'f', -1, 64)), nil
+	case bool:
+		return String(strconv.FormatBool(v)), nil
+	default:
+		return nil, fmt.Errorf("property %s: %T is not a string, number or boolean", k, v)
+	}
+}
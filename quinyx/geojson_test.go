@@ -0,0 +1,125 @@
+package quinyx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestTagsGeoJSONRoundTrip(t *testing.T) {
+	tags := []*Tag{
+		{
+			ExternalID:   String("store-1"),
+			Name:         String("Store 1"),
+			Code:         String("S1"),
+			CustomFields: []*CustomField{{Label: String("region"), Value: String("north")}},
+			Coordinates: []*Coordinate{
+				{Latitude: Float64(59.33), Longitude: Float64(18.06), Radius: Int32(100)},
+				{Latitude: Float64(59.34), Longitude: Float64(18.07), Radius: Int32(50)},
+			},
+		},
+		{ExternalID: String("office"), Name: String("Office")},
+	}
+	var buf bytes.Buffer
+	assert.NilError(t, WriteTagsGeoJSON(&buf, tags))
+	assert.Assert(t, strings.Contains(buf.String(), `"geometry":{"type":"Point","coordinates":[18.06,59.33]}`))
+	assert.Assert(t, strings.Contains(buf.String(), `"custom:region":"north"`))
+
+	back, err := ReadTagsGeoJSON(&buf)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, tags, back)
+}
+
+func TestReadTagsGeoJSONProperties(t *testing.T) {
+	tags, err := ReadTagsGeoJSON(strings.NewReader(`{"type":"FeatureCollection","features":[{"type":"Feature",
+		"properties":{"externalId":"a","name":null,"code":10000000,"custom:open":true,"custom:size":1.5,"custom:note":null}}]}`))
+	assert.NilError(t, err)
+	assert.Assert(t, tags[0].Name == nil)
+	assert.Equal(t, "10000000", *tags[0].Code)
+	assert.DeepEqual(t, []*CustomField{
+		{Label: String("open"), Value: String("true")},
+		{Label: String("size"), Value: String("1.5")},
+	}, tags[0].CustomFields)
+
+	_, err = ReadTagsGeoJSON(strings.NewReader(`{"type":"FeatureCollection","features":[{"type":"Feature",
+		"properties":{"externalId":"a","name":{"en":"A"}}}]}`))
+	assert.Error(t, err, "feature 0: property name: map[string]interface {} is not a string, number or boolean")
+}
+
+func TestReadTagsGeoJSONErrors(t *testing.T) {
+	_, err := ReadTagsGeoJSON(strings.NewReader(`{"type":"Feature"}`))
+	assert.ErrorContains(t, err, "not FeatureCollection")
+	_, err = ReadTagsGeoJSON(strings.NewReader(`{"type":"FeatureCollection","features":[{"type":"Feature","properties":{}}]}`))
+	assert.ErrorContains(t, err, "feature 0: property externalId is missing")
+	_, err = ReadTagsGeoJSON(strings.NewReader(`{"type":"FeatureCollection","features":[{"type":"Feature",
+		"geometry":{"type":"LineString","coordinates":[[1,2],[3,4]]},"properties":{"externalId":"a"}}]}`))
+	assert.ErrorContains(t, err, "must be a Point")
+	_, err = ReadTagsGeoJSON(strings.NewReader(`{"type":"FeatureCollection","features":[{"type":"Feature",
+		"geometry":{"type":"Point","coordinates":[200,2]},"properties":{"externalId":"a","radius":5}}]}`))
+	assert.ErrorContains(t, err, "tag a: coordinate 0: longitude 200 is outside")
+}
+
+func TestImportGeoJSON(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var calls []string
+	mux.HandleFunc("/tags/categories/sites/tags", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, `[{"categoryExternalId":"sites","externalId":"same","name":"Same","coordinates":[{"latitude":1,"longitude":2,"radius":3}]},
+				{"categoryExternalId":"sites","externalId":"moved","name":"Moved","coordinates":[{"latitude":1,"longitude":2,"radius":3}]}]`)
+			return
+		}
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/tags/categories/sites/tags/moved", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PUT")
+		var tag Tag
+		assert.NilError(t, json.NewDecoder(r.Body).Decode(&tag))
+		assert.Equal(t, float64(5), *tag.Coordinates[0].Latitude)
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		fmt.Fprint(w, `{}`)
+	})
+
+	in := `{"type":"FeatureCollection","features":[
+		{"type":"Feature","geometry":{"type":"Point","coordinates":[2,1]},"properties":{"externalId":"same","name":"Same","radius":3}},
+		{"type":"Feature","geometry":{"type":"Point","coordinates":[2,5]},"properties":{"externalId":"moved","name":"Moved","radius":3}},
+		{"type":"Feature","geometry":{"type":"Point","coordinates":[2,9]},"properties":{"externalId":"new","radius":3}}]}`
+	res, err := client.Tags.ImportGeoJSON(context.Background(), "sites", strings.NewReader(in))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &TagImportResult{Created: []string{"new"}, Updated: []string{"moved"}, Unchanged: []string{"same"}}, res)
+	assert.DeepEqual(t, []string{"PUT /tags/categories/sites/tags/moved", "POST /tags/categories/sites/tags"}, calls)
+
+	var buf bytes.Buffer
+	assert.NilError(t, client.Tags.ExportGeoJSON(context.Background(), "sites", &buf))
+	assert.Assert(t, strings.Contains(buf.String(), `"externalId":"moved"`))
+}
+
+func TestImportTagsErrors(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/tags/categories/sites/tags", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, `[null]`)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"message":"bad tag"}`)
+	})
+
+	tags := []*Tag{{ExternalID: String("a"), Name: String("A")}}
+	res, err := client.Tags.importTags(context.Background(), "sites", tags)
+	var rerr *ErrorResponse
+	assert.Assert(t, errors.As(err, &rerr), "%v", err)
+	assert.Equal(t, 0, len(res.Created))
+	assert.Assert(t, tags[0].CategoryExternalID == nil)
+}
@@ -0,0 +1,452 @@
+package quinyx
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// holidayDate is the date format used in holiday CSV files
+const holidayDate = "2006-01-02"
+
+// icsPercentageProp overrides the default percentage for a single holiday in an ICS file
+const icsPercentageProp = "X-QUINYX-PERCENTAGE"
+
+// Holiday is a day on which the calculated forecast is adjusted
+type Holiday struct {
+	// Date of the holiday, only year, month and day are used
+	Date time.Time
+	// Name of the holiday, optional
+	Name string
+	// Percentage is sent as PercentageModification
+	Percentage float64
+}
+
+// ReadHolidaysCSV reads holidays from CSV rows of date (YYYY-MM-DD), percentage
+// and an optional name. A header row is skipped if present. Errors in the file are
+// returned as *ParseError.
+func ReadHolidaysCSV(r io.Reader) ([]*Holiday, error) {
+	cr, lines := newCSVReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	var holidays []*Holiday
+	for first := true; ; first = false {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			return holidays, nil
+		}
+		if err != nil {
+			if pe, ok := err.(*csv.ParseError); ok {
+				return nil, &ParseError{Line: pe.Line, Err: pe.Err}
+			}
+			return nil, err
+		}
+		line := lines.recordLine(rec)
+		if len(rec) < 2 {
+			return nil, &ParseError{Line: line, Err: fmt.Errorf("expected date and percentage")}
+		}
+		date, err := time.Parse(holidayDate, strings.TrimSpace(rec[0]))
+		if err != nil {
+			if first {
+				continue // header
+			}
+			return nil, &ParseError{Line: line, Err: err}
+		}
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(rec[1]), "%"), 64)
+		if err != nil {
+			return nil, &ParseError{Line: line, Err: err}
+		}
+		h := &Holiday{Date: date, Percentage: pct}
+		if len(rec) > 2 {
+			h.Name = strings.TrimSpace(rec[2])
+		}
+		holidays = append(holidays, h)
+	}
+}
+
+// ReadHolidaysICS reads holidays from the VEVENTs of an ICS file. Every day
+// covered by an event becomes a holiday named after its SUMMARY, adjusted by
+// percentage unless the event carries an X-QUINYX-PERCENTAGE property.
+//
+// Only days in [from, to) are returned, a zero from or to leaves that side open.
+// A yearly RRULE, such as FREQ=YEARLY for a fixed date holiday, is expanded within
+// the range and needs UNTIL, COUNT or a to date. Other recurrences are returned as
+// a *ParseError.
+func ReadHolidaysICS(r io.Reader, percentage float64, from, to time.Time) ([]*Holiday, error) {
+	props, err := readICSProps(r)
+	if err != nil {
+		return nil, err
+	}
+	var holidays []*Holiday
+	for _, e := range icsEvents(props) {
+		dtstart := e.get("DTSTART")
+		if dtstart == nil {
+			return nil, &ParseError{Line: e.line, Err: fmt.Errorf("DTSTART missing")}
+		}
+		start, _, err := parseICSTime(dtstart)
+		if err != nil {
+			return nil, &ParseError{Line: dtstart.line, Err: err}
+		}
+		// DTEND is exclusive, an event without one covers a single day
+		days := 1
+		if dtend := e.get("DTEND"); dtend != nil {
+			end, _, err := parseICSTime(dtend)
+			if err != nil {
+				return nil, &ParseError{Line: dtend.line, Err: err}
+			}
+			if n := int(civilDate(end).Sub(civilDate(start)).Hours() / 24); n > days {
+				days = n
+			}
+		}
+		pct := percentage
+		if p := e.get(icsPercentageProp); p != nil && p.value != "" {
+			if pct, err = strconv.ParseFloat(p.value, 64); err != nil {
+				return nil, &ParseError{Line: p.line, Err: fmt.Errorf("%s: %v", icsPercentageProp, err)}
+			}
+		}
+		for _, name := range []string{"RDATE", "EXDATE", "EXRULE"} {
+			if p := e.get(name); p != nil {
+				return nil, &ParseError{Line: p.line, Err: fmt.Errorf("%s is not supported", name)}
+			}
+		}
+		starts := []time.Time{civilDate(start)}
+		if rrule := e.get("RRULE"); rrule != nil {
+			if starts, err = yearlyOccurrences(rrule, dtstart, start, to); err != nil {
+				return nil, &ParseError{Line: rrule.line, Err: err}
+			}
+		}
+		for _, first := range starts {
+			for i := 0; i < days; i++ {
+				d := first.AddDate(0, 0, i)
+				if (!from.IsZero() && d.Before(civilDate(from))) || (!to.IsZero() && !d.Before(civilDate(to))) {
+					continue
+				}
+				holidays = append(holidays, &Holiday{
+					Date:       d,
+					Name:       icsUnescape(e.value("SUMMARY")),
+					Percentage: pct,
+				})
+			}
+		}
+	}
+	return holidays, nil
+}
+
+// yearlyOccurrences returns the dates a FREQ=YEARLY RRULE starting at start occurs on
+// before to. Years without the date, February 29, are skipped as RFC 5545 requires.
+func yearlyOccurrences(rrule, dtstart *icsProp, start, to time.Time) ([]time.Time, error) {
+	first := civilDate(start)
+	var until time.Time
+	count, interval := 0, 1
+	freq := ""
+	for _, part := range strings.Split(rrule.value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("malformed RRULE part %q", part)
+		}
+		key, val := strings.ToUpper(kv[0]), kv[1]
+		var err error
+		switch key {
+		case "FREQ":
+			freq = strings.ToUpper(val)
+		case "INTERVAL":
+			if interval, err = strconv.Atoi(val); err != nil || interval < 1 {
+				return nil, fmt.Errorf("invalid INTERVAL %q", val)
+			}
+		case "COUNT":
+			if count, err = strconv.Atoi(val); err != nil || count < 1 {
+				return nil, fmt.Errorf("invalid COUNT %q", val)
+			}
+		case "UNTIL":
+			if until, _, err = parseICSTime(&icsProp{value: val, params: dtstart.params}); err != nil {
+				return nil, fmt.Errorf("UNTIL: %v", err)
+			}
+			until = civilDate(until.In(start.Location()))
+		case "BYMONTH":
+			// Allowed when it only repeats the month of DTSTART
+			if val != strconv.Itoa(int(first.Month())) {
+				return nil, fmt.Errorf("BYMONTH=%s is not supported", val)
+			}
+		case "BYMONTHDAY":
+			if val != strconv.Itoa(first.Day()) {
+				return nil, fmt.Errorf("BYMONTHDAY=%s is not supported", val)
+			}
+		case "WKST":
+		default:
+			return nil, fmt.Errorf("RRULE %s is not supported", key)
+		}
+	}
+	if freq != "YEARLY" {
+		return nil, fmt.Errorf("FREQ %q is not supported, only YEARLY", freq)
+	}
+	if until.IsZero() && count == 0 && to.IsZero() {
+		return nil, fmt.Errorf("recurrence without UNTIL or COUNT needs a to date")
+	}
+	var dates []time.Time
+	for years := 0; first.Year()+years <= 9999; years += interval {
+		d := first.AddDate(years, 0, 0)
+		if !until.IsZero() && d.After(until) {
+			break
+		}
+		if !to.IsZero() && !d.Before(civilDate(to)) {
+			break
+		}
+		if d.Day() != first.Day() {
+			continue // no such date this year
+		}
+		dates = append(dates, d)
+		if count > 0 && len(dates) == count {
+			break
+		}
+	}
+	return dates, nil
+}
+
+// civilDate returns midnight UTC on the date of t in its own location
+func civilDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// HolidayTarget is a forecast variable and configuration in a unit to adjust
+type HolidayTarget struct {
+	ExternalForecastVariableID      string
+	ExternalForecastConfigurationID string
+	Options                         *RequestOptions
+}
+
+// HolidayAdjustment is a single edit of the calculated forecast for one holiday and target
+type HolidayAdjustment struct {
+	Holiday *Holiday
+	Target  *HolidayTarget
+	Request *EditCalculatedRequest
+}
+
+// Key identifies the adjustment in a HolidayLog. The percentage is deliberately
+// not part of the key, so changing it does not stack a second edit on the same day.
+func (a *HolidayAdjustment) Key() string {
+	unit, section := "", ""
+	if a.Target.Options != nil && a.Target.Options.ExternalUnitID != nil {
+		unit = *a.Target.Options.ExternalUnitID
+	}
+	if a.Target.Options != nil && a.Target.Options.ExternalSectionID != nil {
+		section = *a.Target.Options.ExternalSectionID
+	}
+	return strings.Join([]string{
+		a.Target.ExternalForecastVariableID,
+		a.Target.ExternalForecastConfigurationID,
+		unit,
+		section,
+		a.Holiday.Date.Format(holidayDate),
+	}, "|")
+}
+
+// HolidayPreview shows the calculated forecast of a holiday before and after the adjustment
+type HolidayPreview struct {
+	Adjustment *HolidayAdjustment
+	// Current is the calculated forecast for the holiday as returned by Quinyx
+	Current []*CalculatedPayload
+	// Projected is the value of each Current slot after the adjustment
+	Projected []float64
+	// Applied is true when the adjustment is already in the log
+	Applied bool
+}
+
+// HolidayLogEntry records an applied adjustment
+type HolidayLogEntry struct {
+	Key        string    `json:"key"`
+	Name       string    `json:"name,omitempty"`
+	Percentage float64   `json:"percentage"`
+	AppliedAt  time.Time `json:"appliedAt"`
+}
+
+// HolidayLog remembers which adjustments have been applied
+type HolidayLog interface {
+	Contains(key string) (bool, error)
+	Record(entry *HolidayLogEntry) error
+}
+
+// FileHolidayLog is a HolidayLog stored as JSON lines in a file
+type FileHolidayLog struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]*HolidayLogEntry
+}
+
+// NewFileHolidayLog opens the log at path, creating it on the first Record
+func NewFileHolidayLog(path string) (*FileHolidayLog, error) {
+	l := &FileHolidayLog{path: path, entries: map[string]*HolidayLogEntry{}}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		e := &HolidayLogEntry{}
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, line, err)
+		}
+		l.entries[e.Key] = e
+	}
+	return l, scanner.Err()
+}
+
+// Contains reports whether key has been recorded
+func (l *FileHolidayLog) Contains(key string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, ok := l.entries[key]
+	return ok, nil
+}
+
+// Record appends entry to the log file
+func (l *FileHolidayLog) Record(entry *HolidayLogEntry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(b, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	l.entries[entry.Key] = entry
+	return nil
+}
+
+// HolidayAdjuster turns holidays into edits of the calculated forecast
+type HolidayAdjuster struct {
+	Forecast *ForecastService
+	// Log is consulted before applying an adjustment and updated after, required by Apply
+	Log HolidayLog
+	// Location defines where a holiday starts and ends, defaults to UTC
+	Location *time.Location
+}
+
+// Plan returns one adjustment per holiday and target, each covering the whole local day
+func (h *HolidayAdjuster) Plan(holidays []*Holiday, targets []*HolidayTarget) []*HolidayAdjustment {
+	loc := h.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	var adjs []*HolidayAdjustment
+	for _, hol := range holidays {
+		start := time.Date(hol.Date.Year(), hol.Date.Month(), hol.Date.Day(), 0, 0, 0, 0, loc)
+		end := start.AddDate(0, 0, 1)
+		for _, t := range targets {
+			adjs = append(adjs, &HolidayAdjustment{
+				Holiday: hol,
+				Target:  t,
+				Request: &EditCalculatedRequest{
+					StartTime:              Timestamp{start},
+					EndTime:                Timestamp{end},
+					PercentageModification: hol.Percentage,
+					RepetitionEndDate:      Timestamp{end},
+				},
+			})
+		}
+	}
+	return adjs
+}
+
+// Preview fetches the calculated forecast covered by each adjustment and projects the
+// result of applying it, based on EditedData where present and Data otherwise.
+func (h *HolidayAdjuster) Preview(ctx context.Context, adjs []*HolidayAdjustment) ([]*HolidayPreview, error) {
+	previews := make([]*HolidayPreview, 0, len(adjs))
+	for _, a := range adjs {
+		p := &HolidayPreview{Adjustment: a}
+		if h.Log != nil {
+			applied, err := h.Log.Contains(a.Key())
+			if err != nil {
+				return previews, err
+			}
+			p.Applied = applied
+		}
+		opts := &RequestRangeOptions{
+			StartTime: a.Request.StartTime.Time,
+			EndTime:   a.Request.EndTime.Time,
+		}
+		if a.Target.Options != nil {
+			opts.ExternalUnitID = a.Target.Options.ExternalUnitID
+			opts.ExternalSectionID = a.Target.Options.ExternalSectionID
+		}
+		forecasts, _, err := h.Forecast.GetCalculatedForecast(ctx, a.Target.ExternalForecastVariableID, opts)
+		if err != nil {
+			return previews, err
+		}
+		for _, cf := range forecasts {
+			if cf.ExternalForecastConfigurationID != nil && *cf.ExternalForecastConfigurationID != a.Target.ExternalForecastConfigurationID {
+				continue
+			}
+			for _, slot := range cf.DataPayload {
+				base := slot.Data
+				if slot.EditedData != nil {
+					base = slot.EditedData
+				}
+				if base == nil {
+					continue
+				}
+				p.Current = append(p.Current, slot)
+				p.Projected = append(p.Projected, *base*(1+a.Request.PercentageModification/100))
+			}
+		}
+		previews = append(previews, p)
+	}
+	return previews, nil
+}
+
+// Apply sends every adjustment not yet in the log with EditCalculatedForecast and
+// records it. It returns the adjustments applied in this run.
+func (h *HolidayAdjuster) Apply(ctx context.Context, adjs []*HolidayAdjustment) ([]*HolidayAdjustment, error) {
+	if h.Log == nil {
+		return nil, fmt.Errorf("HolidayAdjuster requires a Log to apply adjustments")
+	}
+	var applied []*HolidayAdjustment
+	for _, a := range adjs {
+		key := a.Key()
+		done, err := h.Log.Contains(key)
+		if err != nil {
+			return applied, err
+		}
+		if done {
+			continue
+		}
+		_, err = h.Forecast.EditCalculatedForecast(ctx, a.Target.ExternalForecastVariableID, a.Target.ExternalForecastConfigurationID, a.Target.Options, a.Request)
+		if err != nil {
+			return applied, err
+		}
+		err = h.Log.Record(&HolidayLogEntry{
+			Key:        key,
+			Name:       a.Holiday.Name,
+			Percentage: a.Holiday.Percentage,
+			AppliedAt:  time.Now().UTC(),
+		})
+		if err != nil {
+			return applied, err
+		}
+		applied = append(applied, a)
+	}
+	return applied, nil
+}
@@ -0,0 +1,161 @@
+package quinyx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestReadHolidaysCSV(t *testing.T) {
+	holidays, err := ReadHolidaysCSV(strings.NewReader("date,percent,name\n2020-12-24, 30%, Christmas Eve\n2020-12-31,-10\n"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []*Holiday{
+		{Date: time.Date(2020, time.December, 24, 0, 0, 0, 0, time.UTC), Name: "Christmas Eve", Percentage: 30},
+		{Date: time.Date(2020, time.December, 31, 0, 0, 0, 0, time.UTC), Percentage: -10},
+	}, holidays)
+
+	_, err = ReadHolidaysCSV(strings.NewReader("2020-12-24,30\n2020-13-01,1\n"))
+	assert.ErrorContains(t, err, "line 2: ")
+
+	// The quoted name spans two lines, so the bad date is on line 3
+	_, err = ReadHolidaysCSV(strings.NewReader("2020-12-24,30,\"Christmas\nEve\"\n2020-13-01,1\n"))
+	var pe *ParseError
+	assert.Assert(t, errors.As(err, &pe))
+	assert.Equal(t, 3, pe.Line)
+}
+
+func TestReadHolidaysICS(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20201224",
+		"DTEND;VALUE=DATE:20201227",
+		"SUMMARY:Christmas",
+		"X-QUINYX-PERCENTAGE:-50",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20210101",
+		"SUMMARY:New year",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	holidays, err := ReadHolidaysICS(strings.NewReader(ics), 20, time.Time{}, time.Time{})
+	assert.NilError(t, err)
+	assert.Equal(t, 4, len(holidays))
+	assert.Equal(t, time.Date(2020, time.December, 26, 0, 0, 0, 0, time.UTC), holidays[2].Date)
+	assert.Equal(t, float64(-50), holidays[2].Percentage)
+	assert.DeepEqual(t, &Holiday{Date: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC), Name: "New year", Percentage: 20}, holidays[3])
+
+	holidays, err = ReadHolidaysICS(strings.NewReader(ics), 20, time.Date(2020, time.December, 25, 0, 0, 0, 0, time.UTC), time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC))
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(holidays))
+}
+
+func TestReadHolidaysICSRecurrence(t *testing.T) {
+	event := func(lines ...string) string {
+		return strings.Join(append(append([]string{"BEGIN:VCALENDAR", "BEGIN:VEVENT"}, lines...), "END:VEVENT", "END:VCALENDAR"), "\r\n")
+	}
+	dates := func(holidays []*Holiday) []string {
+		var got []string
+		for _, h := range holidays {
+			got = append(got, h.Date.Format(holidayDate))
+		}
+		return got
+	}
+	from := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	yearly := event("DTSTART;VALUE=DATE:20191224", "DTEND;VALUE=DATE:20191226", "RRULE:FREQ=YEARLY;BYMONTH=12;BYMONTHDAY=24", "SUMMARY:Christmas")
+	holidays, err := ReadHolidaysICS(strings.NewReader(yearly), 10, from, to)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"2020-12-24", "2020-12-25", "2021-12-24", "2021-12-25", "2022-12-24", "2022-12-25"}, dates(holidays))
+
+	leap := event("DTSTART;VALUE=DATE:20200229", "RRULE:FREQ=YEARLY;COUNT=2")
+	holidays, err = ReadHolidaysICS(strings.NewReader(leap), 10, time.Time{}, time.Time{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"2020-02-29", "2024-02-29"}, dates(holidays))
+
+	until := event("DTSTART;VALUE=DATE:20200501", "RRULE:FREQ=YEARLY;INTERVAL=2;UNTIL=20241231")
+	holidays, err = ReadHolidaysICS(strings.NewReader(until), 10, time.Time{}, time.Time{})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"2020-05-01", "2022-05-01", "2024-05-01"}, dates(holidays))
+
+	_, err = ReadHolidaysICS(strings.NewReader(event("DTSTART;VALUE=DATE:20200501", "RRULE:FREQ=YEARLY")), 10, from, time.Time{})
+	assert.Error(t, err, "line 4: recurrence without UNTIL or COUNT needs a to date")
+	_, err = ReadHolidaysICS(strings.NewReader(event("DTSTART;VALUE=DATE:20200501", "RRULE:FREQ=WEEKLY;COUNT=3")), 10, from, to)
+	var pe *ParseError
+	assert.Assert(t, errors.As(err, &pe))
+	assert.Equal(t, 4, pe.Line)
+	assert.ErrorContains(t, err, `FREQ "WEEKLY" is not supported`)
+	_, err = ReadHolidaysICS(strings.NewReader(event("DTSTART;VALUE=DATE:20200501", "EXDATE;VALUE=DATE:20210501")), 10, from, to)
+	assert.Error(t, err, "line 4: EXDATE is not supported")
+}
+
+func TestHolidayAdjuster(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/forecasts/forecast-variables/sales/calculated-forecast", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		assert.Equal(t, "2020-12-24T00:00:00+01:00", r.URL.Query().Get("startTime"))
+		fmt.Fprint(w, `[
+			{"externalForecastConfigurationId":"other","dataPayload":[{"data":1}]},
+			{"externalForecastConfigurationId":"cfg","dataPayload":[{"data":100},{"data":100,"editedData":200}]}
+		]`)
+	})
+	edits := 0
+	mux.HandleFunc("/forecasts/forecast-variables/sales/forecast-configurations/cfg/edit-forecast", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		assert.Equal(t, "u", r.URL.Query().Get("externalUnitId"))
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NilError(t, err)
+		req := &EditCalculatedRequest{}
+		assert.NilError(t, json.Unmarshal(body, req))
+		assert.Equal(t, float64(50), req.PercentageModification)
+		assert.Equal(t, 24*time.Hour, req.EndTime.Sub(req.StartTime.Time))
+		edits++
+	})
+
+	dir, err := ioutil.TempDir("", "holidays")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	log, err := NewFileHolidayLog(filepath.Join(dir, "applied.jsonl"))
+	assert.NilError(t, err)
+
+	loc, err := time.LoadLocation("Europe/Stockholm")
+	assert.NilError(t, err)
+	adjuster := &HolidayAdjuster{Forecast: client.Forecast, Log: log, Location: loc}
+	adjs := adjuster.Plan(
+		[]*Holiday{{Date: time.Date(2020, time.December, 24, 0, 0, 0, 0, time.UTC), Percentage: 50}},
+		[]*HolidayTarget{{ExternalForecastVariableID: "sales", ExternalForecastConfigurationID: "cfg", Options: &RequestOptions{ExternalUnitID: String("u")}}},
+	)
+	assert.Equal(t, "sales|cfg|u||2020-12-24", adjs[0].Key())
+
+	previews, err := adjuster.Preview(context.Background(), adjs)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []float64{150, 300}, previews[0].Projected)
+	assert.Assert(t, !previews[0].Applied)
+
+	applied, err := adjuster.Apply(context.Background(), adjs)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(applied))
+
+	// A rerun, even with a fresh log instance, does not apply it again
+	log, err = NewFileHolidayLog(filepath.Join(dir, "applied.jsonl"))
+	assert.NilError(t, err)
+	adjuster.Log = log
+	applied, err = adjuster.Apply(context.Background(), adjs)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(applied))
+	assert.Equal(t, 1, edits)
+}
@@ -0,0 +1,551 @@
+package quinyx
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RFC 5545 date and date-time formats
+const (
+	icsDate     = "20060102"
+	icsDateTime = "20060102T150405"
+)
+
+// X- properties used to carry StaticRule fields that have no iCalendar equivalent
+const (
+	icsShiftTypeProp   = "X-QUINYX-SHIFT-TYPE"
+	icsShiftAmountProp = "X-QUINYX-SHIFT-AMOUNT"
+)
+
+// maxRecurrenceDays bounds the walk used to resolve COUNT into an end date
+const maxRecurrenceDays = 366 * 20
+
+var icsWeekdays = map[Weekday]string{
+	Monday:    "MO",
+	Tuesday:   "TU",
+	Wednesday: "WE",
+	Thursday:  "TH",
+	Friday:    "FR",
+	Saturday:  "SA",
+	Sunday:    "SU",
+}
+
+var allWeekdays = []Weekday{Monday, Tuesday, Wednesday, Thursday, Friday, Saturday, Sunday}
+
+var icsDurationRegexp = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// RecurrenceIssue describes a recurrence that could not be represented when
+// converting between StaticRules and iCalendar events
+type RecurrenceIssue struct {
+	// UID of the event, or ExternalID of the rule
+	UID string
+	// Line of the VEVENT in the ICS input, 0 when exporting
+	Line int
+	// Reason describes what could not be represented
+	Reason string
+}
+
+func (i *RecurrenceIssue) Error() string {
+	if i.Line > 0 {
+		return fmt.Sprintf("line %d: event %q: %s", i.Line, i.UID, i.Reason)
+	}
+	return fmt.Sprintf("rule %q: %s", i.UID, i.Reason)
+}
+
+// WriteStaticRulesICS writes the rules as a VCALENDAR with one VEVENT per rule.
+// Comment becomes SUMMARY, ExternalID becomes UID and the weekdays, repeat period
+// and end date become an RRULE. Rules that lose information in the conversion,
+// such as sub-second start times, are reported as issues but still written.
+func WriteStaticRulesICS(w io.Writer, rules []*StaticRule) ([]*RecurrenceIssue, error) {
+	var issues []*RecurrenceIssue
+	iw := &icsWriter{w: bufio.NewWriter(w)}
+	stamp := time.Now().UTC().Format(icsDateTime) + "Z"
+
+	iw.prop("BEGIN", "VCALENDAR")
+	iw.prop("VERSION", "2.0")
+	iw.prop("PRODID", "-//go-quinyx//StaticRule//EN")
+	for _, r := range rules {
+		if r == nil {
+			continue
+		}
+		issue := func(reason string) {
+			issues = append(issues, &RecurrenceIssue{UID: r.ExternalID, Reason: reason})
+		}
+		if r.StartTime.Nano != 0 || r.EndTime.Nano != 0 {
+			issue("sub-second precision dropped")
+		}
+
+		weekdays := make(map[Weekday]bool, len(r.Weekdays))
+		var byDay []string
+		for _, wd := range r.Weekdays {
+			d, ok := icsWeekdays[wd]
+			if !ok {
+				issue(fmt.Sprintf("unknown weekday %q dropped", wd))
+				continue
+			}
+			weekdays[wd] = true
+			byDay = append(byDay, d)
+		}
+
+		date := r.StartDate
+		if len(weekdays) > 0 {
+			// DTSTART must be the first occurrence of the recurrence
+			for i := 0; i < 7 && !weekdays[weekdayOf(date)]; i++ {
+				date = date.AddDate(0, 0, 1)
+			}
+		}
+		start := r.StartTime.on(date)
+		end := r.EndTime.on(date)
+		if !end.After(start) {
+			end = end.AddDate(0, 0, 1)
+		}
+
+		rrule := "FREQ=DAILY"
+		if len(byDay) > 0 {
+			rrule = "FREQ=WEEKLY;BYDAY=" + strings.Join(byDay, ",")
+		}
+		if r.RepeatPeriod > 0 {
+			rrule += fmt.Sprintf(";INTERVAL=%d", r.RepeatPeriod)
+		} else if r.RepeatPeriod < 0 {
+			issue(fmt.Sprintf("negative repeat period %d dropped", r.RepeatPeriod))
+		}
+		if !r.EndDate.IsZero() {
+			rrule += ";UNTIL=" + LocalTime{Hour: 23, Minute: 59, Second: 59}.on(r.EndDate).Format(icsDateTime)
+		} else {
+			issue("no end date, the recurrence never ends and ReadStaticRulesICS rejects it")
+		}
+
+		iw.prop("BEGIN", "VEVENT")
+		iw.prop("UID", icsEscape(r.ExternalID))
+		iw.prop("DTSTAMP", stamp)
+		iw.prop("DTSTART", start.Format(icsDateTime))
+		iw.prop("DTEND", end.Format(icsDateTime))
+		iw.prop("RRULE", rrule)
+		iw.prop("SUMMARY", icsEscape(r.Comment))
+		if r.ShiftType.ShiftTypeID != "" {
+			iw.prop(icsShiftTypeProp, icsEscape(r.ShiftType.ShiftTypeID))
+			iw.prop(icsShiftAmountProp, strconv.Itoa(r.ShiftType.Amount))
+		}
+		iw.prop("END", "VEVENT")
+	}
+	iw.prop("END", "VCALENDAR")
+	if iw.err != nil {
+		return issues, iw.err
+	}
+	return issues, iw.w.Flush()
+}
+
+// ReadStaticRulesICS parses the VEVENTs in r into StaticRules. Times are taken as
+// wall clock times in the zone of the event. Events with a recurrence that a
+// StaticRule cannot express, such as monthly rules, EXDATEs or open ended
+// repetition, are not converted and are reported as issues instead.
+func ReadStaticRulesICS(r io.Reader) ([]*StaticRule, []*RecurrenceIssue, error) {
+	props, err := readICSProps(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	var rules []*StaticRule
+	var issues []*RecurrenceIssue
+	for _, event := range icsEvents(props) {
+		rule, issue := event.staticRule()
+		if issue != nil {
+			issues = append(issues, issue)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, issues, nil
+}
+
+// on returns the LocalTime on the date of d, in UTC
+func (lt LocalTime) on(d time.Time) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(), lt.Hour, lt.Minute, lt.Second, lt.Nano, time.UTC)
+}
+
+// weekdayOf returns the Quinyx Weekday of t
+func weekdayOf(t time.Time) Weekday {
+	return allWeekdays[(int(t.Weekday())+6)%7]
+}
+
+// icsProp is a single unfolded content line
+type icsProp struct {
+	name   string
+	params map[string]string
+	value  string
+	line   int
+}
+
+type icsEvent struct {
+	line  int
+	props map[string][]*icsProp
+}
+
+func (e *icsEvent) get(name string) *icsProp {
+	if p := e.props[name]; len(p) > 0 {
+		return p[0]
+	}
+	return nil
+}
+
+func (e *icsEvent) value(name string) string {
+	if p := e.get(name); p != nil {
+		return p.value
+	}
+	return ""
+}
+
+func readICSProps(r io.Reader) ([]*icsProp, error) {
+	var props []*icsProp
+	var cur strings.Builder
+	curLine := 0
+	flush := func() error {
+		if cur.Len() == 0 {
+			return nil
+		}
+		p, err := parseICSProp(cur.String())
+		if err != nil {
+			return fmt.Errorf("line %d: %v", curLine, err)
+		}
+		p.line = curLine
+		props = append(props, p)
+		cur.Reset()
+		return nil
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		l := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t") {
+			cur.WriteString(l[1:])
+			continue
+		}
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		if l == "" {
+			continue
+		}
+		cur.WriteString(l)
+		curLine = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return props, nil
+}
+
+func parseICSProp(l string) (*icsProp, error) {
+	p := &icsProp{params: map[string]string{}}
+	inQuote := false
+	sep := -1
+	for i, c := range l {
+		if c == '"' {
+			inQuote = !inQuote
+		}
+		if c == ':' && !inQuote {
+			sep = i
+			break
+		}
+	}
+	if sep < 0 {
+		return nil, fmt.Errorf("malformed content line %q", l)
+	}
+	p.value = l[sep+1:]
+	parts := strings.Split(l[:sep], ";")
+	p.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		p.params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+	return p, nil
+}
+
+// icsEvents groups the properties of each top level VEVENT, skipping nested components such as VALARM
+func icsEvents(props []*icsProp) []*icsEvent {
+	var events []*icsEvent
+	var cur *icsEvent
+	depth := 0
+	for _, p := range props {
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT") && cur == nil:
+			cur = &icsEvent{line: p.line, props: map[string][]*icsProp{}}
+		case p.name == "BEGIN" && cur != nil:
+			depth++
+		case p.name == "END" && cur != nil && depth > 0:
+			depth--
+		case p.name == "END" && cur != nil && strings.EqualFold(p.value, "VEVENT"):
+			events = append(events, cur)
+			cur = nil
+		case cur != nil && depth == 0:
+			cur.props[p.name] = append(cur.props[p.name], p)
+		}
+	}
+	return events
+}
+
+func (e *icsEvent) staticRule() (*StaticRule, *RecurrenceIssue) {
+	uid := icsUnescape(e.value("UID"))
+	fail := func(format string, args ...interface{}) (*StaticRule, *RecurrenceIssue) {
+		return nil, &RecurrenceIssue{UID: uid, Line: e.line, Reason: fmt.Sprintf(format, args...)}
+	}
+	for _, name := range []string{"EXDATE", "RDATE", "EXRULE"} {
+		if e.get(name) != nil {
+			return fail("%s is not supported", name)
+		}
+	}
+	if len(e.props["RRULE"]) > 1 {
+		return fail("multiple RRULEs are not supported")
+	}
+
+	dtstart := e.get("DTSTART")
+	if dtstart == nil {
+		return fail("DTSTART missing")
+	}
+	start, allDay, err := parseICSTime(dtstart)
+	if err != nil {
+		return fail("DTSTART: %v", err)
+	}
+	var end time.Time
+	switch {
+	case e.get("DTEND") != nil:
+		if end, _, err = parseICSTime(e.get("DTEND")); err != nil {
+			return fail("DTEND: %v", err)
+		}
+	case e.get("DURATION") != nil:
+		d, err := parseICSDuration(e.value("DURATION"))
+		if err != nil {
+			return fail("DURATION: %v", err)
+		}
+		end = start.Add(d)
+	case allDay:
+		end = start.AddDate(0, 0, 1)
+	default:
+		end = start
+	}
+	if end.Before(start) {
+		return fail("event ends before it starts")
+	}
+	if end.Sub(start) > 24*time.Hour {
+		return fail("events longer than a day are not supported")
+	}
+
+	rule := &StaticRule{
+		Comment:    icsUnescape(e.value("SUMMARY")),
+		ExternalID: uid,
+		StartDate:  time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC),
+		StartTime:  LocalTime{Hour: start.Hour(), Minute: start.Minute(), Second: start.Second()},
+		EndTime:    LocalTime{Hour: end.Hour(), Minute: end.Minute(), Second: end.Second()},
+	}
+	if id := e.value(icsShiftTypeProp); id != "" {
+		rule.ShiftType.ShiftTypeID = icsUnescape(id)
+		if a := e.value(icsShiftAmountProp); a != "" {
+			if rule.ShiftType.Amount, err = strconv.Atoi(a); err != nil {
+				return fail("%s: %v", icsShiftAmountProp, err)
+			}
+		}
+	}
+
+	rrule := e.get("RRULE")
+	if rrule == nil {
+		rule.EndDate = rule.StartDate
+		rule.Weekdays = []Weekday{weekdayOf(start)}
+		return rule, nil
+	}
+
+	var until time.Time
+	count := 0
+	interval := 0
+	freq := ""
+	for _, part := range strings.Split(rrule.value, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return fail("malformed RRULE part %q", part)
+		}
+		key, val := strings.ToUpper(kv[0]), kv[1]
+		switch key {
+		case "FREQ":
+			freq = strings.ToUpper(val)
+		case "INTERVAL":
+			if interval, err = strconv.Atoi(val); err != nil || interval < 1 {
+				return fail("invalid INTERVAL %q", val)
+			}
+		case "COUNT":
+			if count, err = strconv.Atoi(val); err != nil || count < 1 {
+				return fail("invalid COUNT %q", val)
+			}
+		case "UNTIL":
+			if until, _, err = parseICSTime(&icsProp{value: val, params: dtstart.params}); err != nil {
+				return fail("UNTIL: %v", err)
+			}
+			until = until.In(start.Location())
+		case "BYDAY":
+			for _, d := range strings.Split(val, ",") {
+				wd, ok := parseICSWeekday(d)
+				if !ok {
+					return fail("BYDAY %q is not supported", d)
+				}
+				rule.Weekdays = append(rule.Weekdays, wd)
+			}
+		case "WKST":
+		default:
+			return fail("RRULE %s is not supported", key)
+		}
+	}
+	switch freq {
+	case "WEEKLY":
+		if interval > 0 {
+			rule.RepeatPeriod = interval
+		}
+		if len(rule.Weekdays) == 0 {
+			rule.Weekdays = []Weekday{weekdayOf(start)}
+		}
+	case "DAILY":
+		if interval > 1 {
+			return fail("daily recurrence with INTERVAL %d is not supported", interval)
+		}
+		if len(rule.Weekdays) == 0 {
+			rule.Weekdays = append([]Weekday(nil), allWeekdays...)
+		}
+	default:
+		return fail("FREQ %q is not supported", freq)
+	}
+
+	switch {
+	case !until.IsZero():
+		rule.EndDate = time.Date(until.Year(), until.Month(), until.Day(), 0, 0, 0, 0, time.UTC)
+	case count > 0:
+		last, ok := rule.nthOccurrence(count)
+		if !ok {
+			return fail("COUNT %d does not end within %d days", count, maxRecurrenceDays)
+		}
+		rule.EndDate = last
+	default:
+		return fail("recurrence without UNTIL or COUNT never ends")
+	}
+	return rule, nil
+}
+
+// nthOccurrence returns the date of the nth occurrence of the rule counting from StartDate
+func (r *StaticRule) nthOccurrence(n int) (time.Time, bool) {
+	days := make(map[Weekday]bool, len(r.Weekdays))
+	for _, wd := range r.Weekdays {
+		days[wd] = true
+	}
+	interval := r.RepeatPeriod
+	if interval < 1 {
+		interval = 1
+	}
+	// Weeks are counted from the Monday of the week containing StartDate
+	weekStart := r.StartDate.AddDate(0, 0, -((int(r.StartDate.Weekday()) + 6) % 7))
+	for i := 0; i < maxRecurrenceDays; i++ {
+		d := r.StartDate.AddDate(0, 0, i)
+		week := int(d.Sub(weekStart).Hours()/24) / 7
+		if days[weekdayOf(d)] && week%interval == 0 {
+			n--
+			if n == 0 {
+				return d, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func parseICSWeekday(s string) (Weekday, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	for wd, d := range icsWeekdays {
+		if d == s {
+			return wd, true
+		}
+	}
+	return "", false
+}
+
+// parseICSTime parses a DATE or DATE-TIME value, honoring TZID and VALUE parameters
+func parseICSTime(p *icsProp) (time.Time, bool, error) {
+	loc := time.UTC
+	if tzid := p.params["TZID"]; tzid != "" {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		loc = l
+	}
+	v := strings.TrimSpace(p.value)
+	if len(v) == len(icsDate) {
+		t, err := time.ParseInLocation(icsDate, v, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err := time.Parse(icsDateTime, strings.TrimSuffix(v, "Z"))
+		return t.In(loc), false, err
+	}
+	t, err := time.ParseInLocation(icsDateTime, v, loc)
+	return t, false, err
+}
+
+func parseICSDuration(s string) (time.Duration, error) {
+	m := icsDurationRegexp.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("malformed duration %q", s)
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		if m[i+2] == "" {
+			continue
+		}
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return 0, err
+		}
+		d += time.Duration(n) * unit
+	}
+	if m[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+var (
+	icsEscaper   = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+	icsUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+)
+
+func icsEscape(s string) string   { return icsEscaper.Replace(s) }
+func icsUnescape(s string) string { return icsUnescaper.Replace(s) }
+
+// icsWriter writes content lines folded at 75 octets as required by RFC 5545
+type icsWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (iw *icsWriter) prop(name, value string) {
+	if iw.err != nil {
+		return
+	}
+	l := name + ":" + value
+	// Continuation lines start with a space which counts towards the limit
+	for limit := 75; len(l) > limit; limit = 74 {
+		cut := limit
+		// Never split a multi-byte character
+		for cut > 0 && l[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		if _, iw.err = iw.w.WriteString(l[:cut] + "\r\n "); iw.err != nil {
+			return
+		}
+		l = l[cut:]
+	}
+	_, iw.err = iw.w.WriteString(l + "\r\n")
+}
@@ -0,0 +1,136 @@
+package quinyx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestStaticRulesICSRoundTrip(t *testing.T) {
+	rules := []*StaticRule{
+		{
+			Comment:      "Black friday, all hands",
+			StartDate:    time.Date(2020, time.November, 23, 0, 0, 0, 0, time.UTC),
+			EndDate:      time.Date(2020, time.December, 31, 0, 0, 0, 0, time.UTC),
+			StartTime:    LocalTime{Hour: 8, Minute: 30},
+			EndTime:      LocalTime{Hour: 17},
+			ExternalID:   "bf",
+			RepeatPeriod: 2,
+			ShiftType:    ShiftType{Amount: 3, ShiftTypeID: "cashier"},
+			Weekdays:     []Weekday{Friday, Saturday},
+		},
+	}
+	var buf bytes.Buffer
+	issues, err := WriteStaticRulesICS(&buf, rules)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(issues))
+	ics := buf.String()
+	assert.Assert(t, strings.Contains(ics, "DTSTART:20201127T083000\r\n"), ics)
+	assert.Assert(t, strings.Contains(ics, "RRULE:FREQ=WEEKLY;BYDAY=FR,SA;INTERVAL=2;UNTIL=20201231T235959\r\n"), ics)
+	assert.Assert(t, strings.Contains(ics, `SUMMARY:Black friday\, all hands`), ics)
+
+	got, issues, err := ReadStaticRulesICS(&buf)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(issues))
+	want := *rules[0]
+	// DTSTART is moved to the first occurrence
+	want.StartDate = time.Date(2020, time.November, 27, 0, 0, 0, 0, time.UTC)
+	assert.DeepEqual(t, []*StaticRule{&want}, got)
+
+	// An open-ended rule is written but reported, as it cannot be read back
+	buf.Reset()
+	open := *rules[0]
+	open.EndDate = time.Time{}
+	issues, err = WriteStaticRulesICS(&buf, []*StaticRule{&open})
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(issues))
+	assert.Error(t, issues[0], `rule "bf": no end date, the recurrence never ends and ReadStaticRulesICS rejects it`)
+	_, issues, err = ReadStaticRulesICS(&buf)
+	assert.NilError(t, err)
+	assert.ErrorContains(t, issues[0], "never ends")
+}
+
+func TestReadStaticRulesICS(t *testing.T) {
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:night",
+		"DTSTART;TZID=Europe/Stockholm:20201201T220000",
+		"DURATION:PT8H",
+		"RRULE:FREQ=DAILY;COUNT=3",
+		"SUMMARY:Night shift with a very long summary that is folded over more than",
+		"  one line",
+		"BEGIN:VALARM",
+		"TRIGGER:-PT15M",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:monthly",
+		"DTSTART:20201201T080000",
+		"RRULE:FREQ=MONTHLY;UNTIL=20211201T000000Z",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:except",
+		"DTSTART:20201201T080000",
+		"RRULE:FREQ=WEEKLY;UNTIL=20211201T000000Z",
+		"EXDATE:20201208T080000",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:forever",
+		"DTSTART;VALUE=DATE:20201201",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:ordinal",
+		"DTSTART:20201201T080000",
+		"RRULE:FREQ=WEEKLY;BYDAY=1MO;COUNT=2",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:once",
+		"DTSTART;VALUE=DATE:20201224",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	rules, issues, err := ReadStaticRulesICS(strings.NewReader(ics))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []*StaticRule{
+		{
+			Comment:    "Night shift with a very long summary that is folded over more than one line",
+			ExternalID: "night",
+			StartDate:  time.Date(2020, time.December, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:    time.Date(2020, time.December, 3, 0, 0, 0, 0, time.UTC),
+			StartTime:  LocalTime{Hour: 22},
+			EndTime:    LocalTime{Hour: 6},
+			Weekdays:   []Weekday{Monday, Tuesday, Wednesday, Thursday, Friday, Saturday, Sunday},
+		},
+		{
+			ExternalID: "once",
+			StartDate:  time.Date(2020, time.December, 24, 0, 0, 0, 0, time.UTC),
+			EndDate:    time.Date(2020, time.December, 24, 0, 0, 0, 0, time.UTC),
+			Weekdays:   []Weekday{Thursday},
+		},
+	}, rules)
+
+	var reported []string
+	for _, i := range issues {
+		reported = append(reported, i.UID)
+	}
+	assert.DeepEqual(t, []string{"monthly", "except", "forever", "ordinal"}, reported)
+	assert.ErrorContains(t, issues[0], `line 13: event "monthly": FREQ "MONTHLY" is not supported`)
+}
+
+func TestStaticRuleNthOccurrence(t *testing.T) {
+	r := &StaticRule{
+		StartDate:    time.Date(2020, time.December, 2, 0, 0, 0, 0, time.UTC), // Wednesday
+		Weekdays:     []Weekday{Monday, Wednesday},
+		RepeatPeriod: 2,
+	}
+	last, ok := r.nthOccurrence(3)
+	assert.Assert(t, ok)
+	// Wed 2nd, Mon 14th, Wed 16th
+	assert.Equal(t, time.Date(2020, time.December, 16, 0, 0, 0, 0, time.UTC), last)
+}
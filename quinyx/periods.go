@@ -0,0 +1,164 @@
+package quinyx
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PeriodError is the error returned for an invalid or overlapping Period of a tag
+type PeriodError struct {
+	// Index of the period in Tag.Periods
+	Index int
+	// Other is the index of the period it overlaps, -1 otherwise
+	Other  int
+	Reason string
+}
+
+func (e *PeriodError) Error() string {
+	if e.Other >= 0 {
+		return fmt.Sprintf("period %d: %s period %d", e.Index, e.Reason, e.Other)
+	}
+	return fmt.Sprintf("period %d: %s", e.Index, e.Reason)
+}
+
+// ValidatePeriods checks that every period has a From before its To, a To when it is
+// of type PERIOD, a positive Count when it repeats every DAYS or WEEKS, no negative
+// Hours, and that no two periods cover the same time. Types this library does not
+// know, see PeriodType.IsKnown, only get the checks that do not depend on the type.
+func ValidatePeriods(periods []*Period) error {
+	for i, p := range periods {
+		if p == nil || p.From == nil {
+			return &PeriodError{Index: i, Other: -1, Reason: "has no from"}
+		}
+		if p.To != nil && !p.To.After(p.From.Time) {
+			return &PeriodError{Index: i, Other: -1, Reason: "ends before it starts"}
+		}
+		if p.Hours != nil && *p.Hours < 0 {
+			return &PeriodError{Index: i, Other: -1, Reason: "has negative hours"}
+		}
+		switch p.Type {
+		case PeriodTypePeriod, "":
+			if p.To == nil {
+				return &PeriodError{Index: i, Other: -1, Reason: "has no to"}
+			}
+		case PeriodTypeDays, PeriodTypeWeeks:
+			if p.Count == nil || *p.Count < 1 {
+				return &PeriodError{Index: i, Other: -1, Reason: "needs a positive count"}
+			}
+		}
+		for j, q := range periods[:i] {
+			if p.overlaps(q) {
+				return &PeriodError{Index: i, Other: j, Reason: "overlaps"}
+			}
+		}
+	}
+	return nil
+}
+
+// overlaps reports whether the spans of two valid periods share any time
+func (p *Period) overlaps(q *Period) bool {
+	return (q.To == nil || p.From.Before(q.To.Time)) && (p.To == nil || q.From.Before(p.To.Time))
+}
+
+// length is the length of one window of a repeating period, 0 for PERIOD
+func (p *Period) length() (days int) {
+	if p.Count == nil {
+		return 0
+	}
+	switch p.Type {
+	case PeriodTypeDays:
+		return int(*p.Count)
+	case PeriodTypeWeeks:
+		return 7 * int(*p.Count)
+	}
+	return 0
+}
+
+// WindowAt returns the budget window of the period that contains at. A PERIOD has a
+// single window from From to To, DAYS and WEEKS repeat a window of Count days or
+// weeks from From, until To if set. ok is false when at is outside the period.
+func (p *Period) WindowAt(at time.Time) (start, end time.Time, ok bool) {
+	if p == nil || p.From == nil || at.Before(p.From.Time) || p.To != nil && !at.Before(p.To.Time) {
+		return time.Time{}, time.Time{}, false
+	}
+	days := p.length()
+	if days == 0 {
+		if p.To == nil {
+			return time.Time{}, time.Time{}, false
+		}
+		return p.From.Time, p.To.Time, true
+	}
+	// Step by calendar days from From so windows follow the clock across DST
+	n := int(at.Sub(p.From.Time).Hours()/24) / days
+	start = p.From.AddDate(0, 0, n*days)
+	for start.After(at) {
+		start = start.AddDate(0, 0, -days)
+	}
+	for !at.Before(start.AddDate(0, 0, days)) {
+		start = start.AddDate(0, 0, days)
+	}
+	end = start.AddDate(0, 0, days)
+	if p.To != nil && end.After(p.To.Time) {
+		end = p.To.Time
+	}
+	return start, end, true
+}
+
+// ActiveAt reports whether the tag has started and not yet ended at the time.
+// StartDate is inclusive and so is the whole day of EndDate, a missing date is open.
+func (t *Tag) ActiveAt(at time.Time) bool {
+	return t.ActiveDuring(at, at.Add(time.Nanosecond))
+}
+
+// ActiveDuring reports whether the tag is active at some time in [from, to)
+func (t *Tag) ActiveDuring(from, to time.Time) bool {
+	if t.StartDate != nil && !t.StartDate.Before(to) {
+		return false
+	}
+	if t.EndDate != nil && !from.Before(endOfDay(t.EndDate.Time)) {
+		return false
+	}
+	return true
+}
+
+// endOfDay returns the start of the day after t, in the location of t
+func endOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location()).AddDate(0, 0, 1)
+}
+
+// PeriodAt returns the period of the tag covering at, with the bounds of its window
+func (t *Tag) PeriodAt(at time.Time) (p *Period, start, end time.Time, ok bool) {
+	for _, p := range t.Periods {
+		if start, end, ok := p.WindowAt(at); ok {
+			return p, start, end, true
+		}
+	}
+	return nil, time.Time{}, time.Time{}, false
+}
+
+// RemainingHours returns the budgeted hours of the period window covering at minus
+// the hours already used in that window. ok is false when no period with hours covers at.
+func (t *Tag) RemainingHours(at time.Time, used float64) (remaining float64, ok bool) {
+	p, _, _, ok := t.PeriodAt(at)
+	if !ok || p.Hours == nil {
+		return 0, false
+	}
+	return *p.Hours - used, true
+}
+
+// ActiveTags returns the tags of the category that are active at some time in [from, to)
+func (s *TagsService) ActiveTags(ctx context.Context, categoryExternalID string, from, to time.Time) ([]*Tag, error) {
+	tags, _, err := s.GetAllTags(ctx, categoryExternalID)
+	if err != nil {
+		return nil, err
+	}
+	var active []*Tag
+	for _, t := range tags {
+		if t.ActiveDuring(from, to) {
+			active = append(active, t)
+		}
+	}
+	return active, nil
+}
@@ -0,0 +1,88 @@
+package quinyx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func testDate(month time.Month, day int) *Timestamp {
+	return &Timestamp{time.Date(2020, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+func TestValidatePeriods(t *testing.T) {
+	assert.NilError(t, ValidatePeriods([]*Period{
+		{From: testDate(time.January, 1), To: testDate(time.February, 1), Type: PeriodTypePeriod},
+		{From: testDate(time.February, 1), Type: PeriodTypeWeeks, Count: Float64(2)},
+	}))
+
+	var perr *PeriodError
+	err := ValidatePeriods([]*Period{{From: testDate(time.February, 1), To: testDate(time.January, 1)}})
+	assert.Assert(t, errors.As(err, &perr))
+	assert.Equal(t, "period 0: ends before it starts", err.Error())
+
+	err = ValidatePeriods([]*Period{
+		{From: testDate(time.January, 1), To: testDate(time.March, 1)},
+		{From: testDate(time.February, 1), Type: PeriodTypeDays, Count: Float64(7)},
+	})
+	assert.Equal(t, "period 1: overlaps period 0", err.Error())
+	assert.ErrorContains(t, ValidatePeriods([]*Period{{From: testDate(time.January, 1), Type: PeriodTypeDays}}), "positive count")
+	assert.ErrorContains(t, ValidatePeriods([]*Period{{From: testDate(time.January, 1)}}), "has no to")
+	assert.ErrorContains(t, ValidatePeriods([]*Period{{From: testDate(time.January, 1), To: testDate(time.January, 2), Hours: Float64(-1)}}), "negative hours")
+
+	client, _, _, teardown := setup()
+	defer teardown()
+	_, _, err = client.Tags.CreateTag(context.Background(), "cc", &Tag{Periods: []*Period{{From: testDate(time.February, 1), To: testDate(time.January, 1)}}})
+	assert.Assert(t, errors.As(err, &perr))
+}
+
+func TestTagPeriods(t *testing.T) {
+	tag := &Tag{
+		StartDate: testDate(time.January, 1),
+		EndDate:   testDate(time.December, 31),
+		Periods: []*Period{
+			{From: testDate(time.January, 1), To: testDate(time.February, 1), Hours: Float64(100)},
+			{From: testDate(time.March, 2), Type: PeriodTypeWeeks, Count: Float64(2), Hours: Float64(40)},
+		},
+	}
+	assert.Assert(t, tag.ActiveAt(testDate(time.June, 1).Time))
+	assert.Assert(t, tag.ActiveAt(testDate(time.December, 31).Time.Add(23*time.Hour)))
+	assert.Assert(t, !tag.ActiveAt(testDate(time.December, 31).Time.AddDate(0, 0, 1)))
+	assert.Assert(t, tag.ActiveDuring(testDate(time.December, 30).Time, testDate(time.December, 31).Time))
+	assert.Assert(t, !tag.ActiveDuring(testDate(time.January, 1).Time.AddDate(1, 0, 0), testDate(time.January, 2).Time.AddDate(1, 0, 0)))
+
+	remaining, ok := tag.RemainingHours(testDate(time.January, 15).Time, 30)
+	assert.Assert(t, ok)
+	assert.Equal(t, float64(70), remaining)
+	_, ok = tag.RemainingHours(testDate(time.February, 15).Time, 0)
+	assert.Assert(t, !ok)
+
+	// Two week windows from March 2: March 16, March 30 (across the DST change in Europe), April 13
+	loc, _ := time.LoadLocation("Europe/Stockholm")
+	tag.Periods[1].From = &Timestamp{time.Date(2020, time.March, 2, 0, 0, 0, 0, loc)}
+	p, start, end, ok := tag.PeriodAt(time.Date(2020, time.April, 1, 12, 0, 0, 0, loc))
+	assert.Assert(t, ok)
+	assert.Equal(t, tag.Periods[1], p)
+	assert.Equal(t, time.Date(2020, time.March, 30, 0, 0, 0, 0, loc), start)
+	assert.Equal(t, time.Date(2020, time.April, 13, 0, 0, 0, 0, loc), end)
+}
+
+func TestActiveTags(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/tags/categories/cc/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"externalId":"old","endDate":"2020-01-01T00:00:00Z"},
+			{"externalId":"open"},
+			{"externalId":"future","startDate":"2021-01-01T00:00:00Z"}]`)
+	})
+	tags, err := client.Tags.ActiveTags(context.Background(), "cc", testDate(time.June, 1).Time, testDate(time.July, 1).Time)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, len(tags))
+	assert.Equal(t, "open", *tags[0].ExternalID)
+}
@@ -0,0 +1,431 @@
+// Code generated by gen-accessors; DO NOT EDIT.
+
+package quinyx
+
+import (
+	"net/http"
+)
+
+// GetData returns the Data field if it's non-nil, zero value otherwise.
+func (a *AggregatedPayload) GetData() float64 {
+	if a == nil || a.Data == nil {
+		return 0
+	}
+	return *a.Data
+}
+
+// GetEndTime returns the EndTime field if it's non-nil, zero value otherwise.
+func (a *AggregatedPayload) GetEndTime() Timestamp {
+	if a == nil || a.EndTime == nil {
+		return Timestamp{}
+	}
+	return *a.EndTime
+}
+
+// GetStartTime returns the StartTime field if it's non-nil, zero value otherwise.
+func (a *AggregatedPayload) GetStartTime() Timestamp {
+	if a == nil || a.StartTime == nil {
+		return Timestamp{}
+	}
+	return *a.StartTime
+}
+
+// GetExternalForecastConfigurationID returns the ExternalForecastConfigurationID field if it's non-nil, zero value otherwise.
+func (c *CalculatedForecast) GetExternalForecastConfigurationID() string {
+	if c == nil || c.ExternalForecastConfigurationID == nil {
+		return ""
+	}
+	return *c.ExternalForecastConfigurationID
+}
+
+// GetExternalSectionID returns the ExternalSectionID field if it's non-nil, zero value otherwise.
+func (c *CalculatedForecast) GetExternalSectionID() string {
+	if c == nil || c.ExternalSectionID == nil {
+		return ""
+	}
+	return *c.ExternalSectionID
+}
+
+// GetExternalUnitID returns the ExternalUnitID field if it's non-nil, zero value otherwise.
+func (c *CalculatedForecast) GetExternalUnitID() string {
+	if c == nil || c.ExternalUnitID == nil {
+		return ""
+	}
+	return *c.ExternalUnitID
+}
+
+// GetData returns the Data field if it's non-nil, zero value otherwise.
+func (c *CalculatedPayload) GetData() float64 {
+	if c == nil || c.Data == nil {
+		return 0
+	}
+	return *c.Data
+}
+
+// GetEditedData returns the EditedData field if it's non-nil, zero value otherwise.
+func (c *CalculatedPayload) GetEditedData() float64 {
+	if c == nil || c.EditedData == nil {
+		return 0
+	}
+	return *c.EditedData
+}
+
+// GetEndTime returns the EndTime field if it's non-nil, zero value otherwise.
+func (c *CalculatedPayload) GetEndTime() Timestamp {
+	if c == nil || c.EndTime == nil {
+		return Timestamp{}
+	}
+	return *c.EndTime
+}
+
+// GetStartTime returns the StartTime field if it's non-nil, zero value otherwise.
+func (c *CalculatedPayload) GetStartTime() Timestamp {
+	if c == nil || c.StartTime == nil {
+		return Timestamp{}
+	}
+	return *c.StartTime
+}
+
+// GetLatitude returns the Latitude field if it's non-nil, zero value otherwise.
+func (c *Coordinate) GetLatitude() float64 {
+	if c == nil || c.Latitude == nil {
+		return 0
+	}
+	return *c.Latitude
+}
+
+// GetLongitude returns the Longitude field if it's non-nil, zero value otherwise.
+func (c *Coordinate) GetLongitude() float64 {
+	if c == nil || c.Longitude == nil {
+		return 0
+	}
+	return *c.Longitude
+}
+
+// GetRadius returns the Radius field if it's non-nil, zero value otherwise.
+func (c *Coordinate) GetRadius() int32 {
+	if c == nil || c.Radius == nil {
+		return 0
+	}
+	return *c.Radius
+}
+
+// GetLabel returns the Label field if it's non-nil, zero value otherwise.
+func (c *CustomField) GetLabel() string {
+	if c == nil || c.Label == nil {
+		return ""
+	}
+	return *c.Label
+}
+
+// GetValue returns the Value field if it's non-nil, zero value otherwise.
+func (c *CustomField) GetValue() string {
+	if c == nil || c.Value == nil {
+		return ""
+	}
+	return *c.Value
+}
+
+// GetExternalForecastVariableID returns the ExternalForecastVariableID field if it's non-nil, zero value otherwise.
+func (d *DataProvider) GetExternalForecastVariableID() string {
+	if d == nil || d.ExternalForecastVariableID == nil {
+		return ""
+	}
+	return *d.ExternalForecastVariableID
+}
+
+// GetExternalSectionID returns the ExternalSectionID field if it's non-nil, zero value otherwise.
+func (d *DataProvider) GetExternalSectionID() string {
+	if d == nil || d.ExternalSectionID == nil {
+		return ""
+	}
+	return *d.ExternalSectionID
+}
+
+// GetExternalUnitID returns the ExternalUnitID field if it's non-nil, zero value otherwise.
+func (d *DataProvider) GetExternalUnitID() string {
+	if d == nil || d.ExternalUnitID == nil {
+		return ""
+	}
+	return *d.ExternalUnitID
+}
+
+// GetExternalForecastVariableID returns the ExternalForecastVariableID field if it's non-nil, zero value otherwise.
+func (d *DataProviderInput) GetExternalForecastVariableID() string {
+	if d == nil || d.ExternalForecastVariableID == nil {
+		return ""
+	}
+	return *d.ExternalForecastVariableID
+}
+
+// GetExternalSectionID returns the ExternalSectionID field if it's non-nil, zero value otherwise.
+func (d *DataProviderInput) GetExternalSectionID() string {
+	if d == nil || d.ExternalSectionID == nil {
+		return ""
+	}
+	return *d.ExternalSectionID
+}
+
+// GetExternalUnitID returns the ExternalUnitID field if it's non-nil, zero value otherwise.
+func (d *DataProviderInput) GetExternalUnitID() string {
+	if d == nil || d.ExternalUnitID == nil {
+		return ""
+	}
+	return *d.ExternalUnitID
+}
+
+// GetResponse returns the Response field.
+func (e *ErrorResponse) GetResponse() *http.Response {
+	if e == nil {
+		return nil
+	}
+	return e.Response
+}
+
+// GetExternalForecastConfigurationID returns the ExternalForecastConfigurationID field if it's non-nil, zero value otherwise.
+func (f *ForecastPrediction) GetExternalForecastConfigurationID() string {
+	if f == nil || f.ExternalForecastConfigurationID == nil {
+		return ""
+	}
+	return *f.ExternalForecastConfigurationID
+}
+
+// GetExternalForecastVariableID returns the ExternalForecastVariableID field if it's non-nil, zero value otherwise.
+func (f *ForecastPrediction) GetExternalForecastVariableID() string {
+	if f == nil || f.ExternalForecastVariableID == nil {
+		return ""
+	}
+	return *f.ExternalForecastVariableID
+}
+
+// GetExternalSectionID returns the ExternalSectionID field if it's non-nil, zero value otherwise.
+func (f *ForecastPrediction) GetExternalSectionID() string {
+	if f == nil || f.ExternalSectionID == nil {
+		return ""
+	}
+	return *f.ExternalSectionID
+}
+
+// GetExternalUnitID returns the ExternalUnitID field if it's non-nil, zero value otherwise.
+func (f *ForecastPrediction) GetExternalUnitID() string {
+	if f == nil || f.ExternalUnitID == nil {
+		return ""
+	}
+	return *f.ExternalUnitID
+}
+
+// GetRunIdentifier returns the RunIdentifier field if it's non-nil, zero value otherwise.
+func (f *ForecastPrediction) GetRunIdentifier() string {
+	if f == nil || f.RunIdentifier == nil {
+		return ""
+	}
+	return *f.RunIdentifier
+}
+
+// GetRunTimestamp returns the RunTimestamp field if it's non-nil, zero value otherwise.
+func (f *ForecastPrediction) GetRunTimestamp() Timestamp {
+	if f == nil || f.RunTimestamp == nil {
+		return Timestamp{}
+	}
+	return *f.RunTimestamp
+}
+
+// GetGeometry returns the Geometry field.
+func (g *GeoJSONFeature) GetGeometry() *GeoJSONGeometry {
+	if g == nil {
+		return nil
+	}
+	return g.Geometry
+}
+
+// GetData returns the Data field if it's non-nil, zero value otherwise.
+func (p *Payload) GetData() float64 {
+	if p == nil || p.Data == nil {
+		return 0
+	}
+	return *p.Data
+}
+
+// GetTimestamp returns the Timestamp field if it's non-nil, zero value otherwise.
+func (p *Payload) GetTimestamp() Timestamp {
+	if p == nil || p.Timestamp == nil {
+		return Timestamp{}
+	}
+	return *p.Timestamp
+}
+
+// GetCount returns the Count field if it's non-nil, zero value otherwise.
+func (p *Period) GetCount() float64 {
+	if p == nil || p.Count == nil {
+		return 0
+	}
+	return *p.Count
+}
+
+// GetFrom returns the From field if it's non-nil, zero value otherwise.
+func (p *Period) GetFrom() Timestamp {
+	if p == nil || p.From == nil {
+		return Timestamp{}
+	}
+	return *p.From
+}
+
+// GetHours returns the Hours field if it's non-nil, zero value otherwise.
+func (p *Period) GetHours() float64 {
+	if p == nil || p.Hours == nil {
+		return 0
+	}
+	return *p.Hours
+}
+
+// GetTo returns the To field if it's non-nil, zero value otherwise.
+func (p *Period) GetTo() Timestamp {
+	if p == nil || p.To == nil {
+		return Timestamp{}
+	}
+	return *p.To
+}
+
+// GetExternalSectionID returns the ExternalSectionID field if it's non-nil, zero value otherwise.
+func (r *RequestOptions) GetExternalSectionID() string {
+	if r == nil || r.ExternalSectionID == nil {
+		return ""
+	}
+	return *r.ExternalSectionID
+}
+
+// GetExternalUnitID returns the ExternalUnitID field if it's non-nil, zero value otherwise.
+func (r *RequestOptions) GetExternalUnitID() string {
+	if r == nil || r.ExternalUnitID == nil {
+		return ""
+	}
+	return *r.ExternalUnitID
+}
+
+// GetExternalSectionID returns the ExternalSectionID field if it's non-nil, zero value otherwise.
+func (r *RequestRangeOptions) GetExternalSectionID() string {
+	if r == nil || r.ExternalSectionID == nil {
+		return ""
+	}
+	return *r.ExternalSectionID
+}
+
+// GetExternalUnitID returns the ExternalUnitID field if it's non-nil, zero value otherwise.
+func (r *RequestRangeOptions) GetExternalUnitID() string {
+	if r == nil || r.ExternalUnitID == nil {
+		return ""
+	}
+	return *r.ExternalUnitID
+}
+
+// GetExternalSectionID returns the ExternalSectionID field if it's non-nil, zero value otherwise.
+func (r *RunWindow) GetExternalSectionID() string {
+	if r == nil || r.ExternalSectionID == nil {
+		return ""
+	}
+	return *r.ExternalSectionID
+}
+
+// GetCategoryExternalID returns the CategoryExternalID field if it's non-nil, zero value otherwise.
+func (t *Tag) GetCategoryExternalID() string {
+	if t == nil || t.CategoryExternalID == nil {
+		return ""
+	}
+	return *t.CategoryExternalID
+}
+
+// GetCode returns the Code field if it's non-nil, zero value otherwise.
+func (t *Tag) GetCode() string {
+	if t == nil || t.Code == nil {
+		return ""
+	}
+	return *t.Code
+}
+
+// GetEndDate returns the EndDate field if it's non-nil, zero value otherwise.
+func (t *Tag) GetEndDate() Timestamp {
+	if t == nil || t.EndDate == nil {
+		return Timestamp{}
+	}
+	return *t.EndDate
+}
+
+// GetExternalID returns the ExternalID field if it's non-nil, zero value otherwise.
+func (t *Tag) GetExternalID() string {
+	if t == nil || t.ExternalID == nil {
+		return ""
+	}
+	return *t.ExternalID
+}
+
+// GetInformation returns the Information field if it's non-nil, zero value otherwise.
+func (t *Tag) GetInformation() string {
+	if t == nil || t.Information == nil {
+		return ""
+	}
+	return *t.Information
+}
+
+// GetName returns the Name field if it's non-nil, zero value otherwise.
+func (t *Tag) GetName() string {
+	if t == nil || t.Name == nil {
+		return ""
+	}
+	return *t.Name
+}
+
+// GetStartDate returns the StartDate field if it's non-nil, zero value otherwise.
+func (t *Tag) GetStartDate() Timestamp {
+	if t == nil || t.StartDate == nil {
+		return Timestamp{}
+	}
+	return *t.StartDate
+}
+
+// GetUniqueScheduling returns the UniqueScheduling field if it's non-nil, zero value otherwise.
+func (t *Tag) GetUniqueScheduling() bool {
+	if t == nil || t.UniqueScheduling == nil {
+		return false
+	}
+	return *t.UniqueScheduling
+}
+
+// GetUnitExternalID returns the UnitExternalID field if it's non-nil, zero value otherwise.
+func (t *Tag) GetUnitExternalID() string {
+	if t == nil || t.UnitExternalID == nil {
+		return ""
+	}
+	return *t.UnitExternalID
+}
+
+// GetColor returns the Color field if it's non-nil, zero value otherwise.
+func (t *TagCategory) GetColor() string {
+	if t == nil || t.Color == nil {
+		return ""
+	}
+	return *t.Color
+}
+
+// GetExternalID returns the ExternalID field if it's non-nil, zero value otherwise.
+func (t *TagCategory) GetExternalID() string {
+	if t == nil || t.ExternalID == nil {
+		return ""
+	}
+	return *t.ExternalID
+}
+
+// GetName returns the Name field if it's non-nil, zero value otherwise.
+func (t *TagCategory) GetName() string {
+	if t == nil || t.Name == nil {
+		return ""
+	}
+	return *t.Name
+}
+
+// GetTagID returns the TagID field if it's non-nil, zero value otherwise.
+func (t *TagCategory) GetTagID() int32 {
+	if t == nil || t.TagID == nil {
+		return 0
+	}
+	return *t.TagID
+}
@@ -1,5 +1,7 @@
 package quinyx
 
+//go:generate go run gen-accessors.go
+
 import (
 	"bytes"
 	"context"
@@ -10,15 +12,19 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 )
 
 const (
 	defaultBaseURL = "https://api.quinyx.com"
 	userAgent      = "go-quinyx"
 
-	headerQuinyxUID = "X-Quinyx-Uid"
+	headerQuinyxUID  = "X-Quinyx-Uid"
+	headerRetryAfter = "Retry-After"
 )
 
 // A Client manages communication with the Quinyx API.
@@ -34,6 +40,26 @@ type Client struct {
 	// User agent used when communicating with the Quinyx API.
 	UserAgent string
 
+	// UploadValidator, if set, validates the data of UploadBudgetData and
+	// UploadPredictedData and blocks uploads it finds too broken to send.
+	UploadValidator *UploadValidator
+
+	// StrictEnums makes Do return an *UnknownEnumError when a response holds a
+	// TagType, PeriodType or Weekday this library does not know. By default
+	// unknown values are kept, so a new value in Quinyx does not break decoding.
+	StrictEnums bool
+
+	// OnUnknownFields, if set, is called with the JSON fields of a response that
+	// the models do not have, see UnknownFields.
+	OnUnknownFields func(*Response, []UnknownField)
+
+	// StrictFields makes Do return an *UnknownFieldsError when a response has
+	// fields the models do not have, for tests that catch API drift.
+	StrictFields bool
+
+	unknownMu     sync.Mutex
+	unknownFields map[string]map[string]bool // Unknown JSON fields by model name.
+
 	common service // Reuse a single struct instead of allocating one for each service on the heap.
 
 	// Services used for talking to different parts of the Quinyx API.
@@ -118,6 +144,73 @@ func (c *Client) NewRequest(method, urlStr string, body interface{}) (*http.Requ
 type Response struct {
 	*http.Response
 	QuinyxUID string
+
+	// These fields provide the page values for paginating through a set of
+	// results. Any or all of these may be set to the zero value for
+	// responses that are not part of a paginated set, or for which there
+	// are no additional pages. Pages are numbered from 0, so NextPage is
+	// never 0 when there is a next page.
+	NextPage  int
+	PrevPage  int
+	FirstPage int
+	LastPage  int
+
+	// NextCursor is the cursor of the next page of cursor paginated results
+	NextCursor string
+}
+
+// ListOptions specifies the optional parameters to methods that support pagination.
+// Use either Page or Cursor.
+type ListOptions struct {
+	// For paginated result sets, page of results to retrieve, starting at 0.
+	Page int `url:"page,omitempty"`
+
+	// For paginated result sets, the number of results to include per page.
+	Size int `url:"size,omitempty"`
+
+	// For cursor paginated result sets, the cursor returned in Response.NextCursor.
+	Cursor string `url:"cursor,omitempty"`
+}
+
+// HasNextPage reports whether the response says there are more results
+func (r *Response) HasNextPage() bool {
+	return r != nil && (r.NextPage > 0 || r.NextCursor != "")
+}
+
+// populatePageValues parses the HTTP Link response headers and populates the
+// various pagination link values in the Response.
+func (r *Response) populatePageValues() {
+	for _, link := range strings.Split(r.Header.Get("Link"), ",") {
+		segments := strings.Split(strings.TrimSpace(link), ";")
+		// link must at least have href and rel
+		if len(segments) < 2 {
+			continue
+		}
+		// ensure href is properly formatted
+		if !strings.HasPrefix(segments[0], "<") || !strings.HasSuffix(segments[0], ">") {
+			continue
+		}
+		// try to pull out page parameter
+		u, err := url.Parse(segments[0][1 : len(segments[0])-1])
+		if err != nil {
+			continue
+		}
+		q := u.Query()
+		page, _ := strconv.Atoi(q.Get("page"))
+		for _, segment := range segments[1:] {
+			switch strings.TrimSpace(segment) {
+			case `rel="next"`:
+				r.NextPage = page
+				r.NextCursor = q.Get("cursor")
+			case `rel="prev"`:
+				r.PrevPage = page
+			case `rel="first"`:
+				r.FirstPage = page
+			case `rel="last"`:
+				r.LastPage = page
+			}
+		}
+	}
 }
 
 // GetQuinyxUID extracts the X-Quinyx-Uid header used for request tracing as a string.
@@ -132,6 +225,7 @@ func (r *Response) GetQuinyxUID() string {
 // r must not be nil.
 func newResponse(r *http.Response) *Response {
 	response := &Response{Response: r, QuinyxUID: r.Header.Get(headerQuinyxUID)}
+	response.populatePageValues()
 	return response
 }
 
@@ -139,8 +233,8 @@ func newResponse(r *http.Response) *Response {
 // JSON decoded and stored in the value pointed to by v, or returned as an
 // error if an API error has occurred. If v implements the io.Writer
 // interface, the raw response body will be written to v, without attempting to
-// first decode it. If rate limit is exceeded and reset time is in the future,
-// Do returns *RateLimitError immediately without making a network API call.
+// first decode it. A 429 Too Many Requests response is returned as a
+// *RateLimitError, see GetAggregatedDataForUnits for calls that wait and retry.
 //
 // The provided ctx must be non-nil, if it is nil an error is returned. If it is canceled or times out,
 // ctx.Err() will be returned.
@@ -197,12 +291,23 @@ func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*Res
 		if w, ok := v.(io.Writer); ok {
 			io.Copy(w, resp.Body)
 		} else {
-			decErr := json.NewDecoder(resp.Body).Decode(v)
+			var body bytes.Buffer
+			var r io.Reader = resp.Body
+			checkFields := c.OnUnknownFields != nil || c.StrictFields
+			if checkFields {
+				r = io.TeeReader(resp.Body, &body)
+			}
+			decErr := json.NewDecoder(r).Decode(v)
 			if decErr == io.EOF {
 				decErr = nil // ignore EOF errors caused by empty response body
 			}
 			if decErr != nil {
 				err = decErr
+			} else if c.StrictEnums {
+				err = checkEnums(reflect.ValueOf(v))
+			}
+			if err == nil && checkFields && body.Len() > 0 {
+				err = c.checkUnknownFields(response, body.Bytes(), v)
 			}
 		}
 	}
@@ -240,11 +345,30 @@ func CheckResponse(r *http.Response) error {
 	// Issue #1136, #540.
 	r.Body = ioutil.NopCloser(bytes.NewBuffer(data))
 	switch {
+	case r.StatusCode == http.StatusTooManyRequests:
+		return &RateLimitError{
+			ErrorResponse: errorResponse,
+			RetryAfter:    parseRetryAfter(r.Header.Get(headerRetryAfter)),
+		}
 	default:
 		return errorResponse
 	}
 }
 
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
 // ErrorResponse ErrorResponse
 type ErrorResponse struct {
 	Response *http.Response // HTTP response that caused this error
@@ -258,6 +382,24 @@ func (r *ErrorResponse) Error() string {
 		r.Response.StatusCode, r.Message, r.Errors)
 }
 
+// RateLimitError occurs when Quinyx returns 429 Too Many Requests. It wraps the
+// *ErrorResponse returned for other failed calls, so errors.As matches either.
+type RateLimitError struct {
+	*ErrorResponse
+	RetryAfter time.Duration // How long to wait before retrying, zero when Quinyx did not say
+}
+
+func (r *RateLimitError) Error() string {
+	return fmt.Sprintf("%v %v: %d %v; retry after %v",
+		r.Response.Request.Method, sanitizeURL(r.Response.Request.URL),
+		r.Response.StatusCode, r.Message, r.RetryAfter)
+}
+
+// Unwrap returns the *ErrorResponse
+func (r *RateLimitError) Unwrap() error {
+	return r.ErrorResponse
+}
+
 // Error Error
 type Error struct {
 	Resource string `json:"resource"` // resource on which the error occurred
@@ -1,10 +1,16 @@
 package quinyx
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
+
+	"gotest.tools/assert"
 )
 
 func setup() (client *Client, mux *http.ServeMux, serverURL string, teardown func()) {
@@ -26,3 +32,34 @@ func testMethod(t *testing.T, r *http.Request, want string) {
 		t.Errorf("Request method: %v, want %v", got, want)
 	}
 }
+
+func TestRateLimitError(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	calls := 0
+	mux.HandleFunc("/limited", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"message":"slow down"}`)
+	})
+
+	req, _ := client.NewRequest("GET", "limited", nil)
+	_, err := client.Do(context.Background(), req, nil)
+	rerr, ok := err.(*RateLimitError)
+	assert.Assert(t, ok)
+	assert.Equal(t, time.Minute, rerr.RetryAfter)
+	assert.Equal(t, "slow down", rerr.Message)
+
+	var eerr *ErrorResponse
+	assert.Assert(t, errors.As(err, &eerr))
+	assert.Equal(t, http.StatusTooManyRequests, eerr.Response.StatusCode)
+
+	// Other calls on the client are not held back
+	req, _ = client.NewRequest("GET", "limited", nil)
+	_, err = client.Do(context.Background(), req, nil)
+	_, ok = err.(*RateLimitError)
+	assert.Assert(t, ok)
+	assert.Equal(t, 2, calls)
+}
@@ -0,0 +1,276 @@
+package quinyx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// ConflictPolicy decides what happens when a copied rule collides with an
+// existing rule in the target unit
+type ConflictPolicy int
+
+// ConflictPolicies
+const (
+	// ConflictSkip leaves the existing rule untouched
+	ConflictSkip ConflictPolicy = iota
+	// ConflictOverwrite replaces the existing rule with the copied one
+	ConflictOverwrite
+	// ConflictFail aborts the copy before anything is written
+	ConflictFail
+)
+
+// ErrorShiftTypeUnmapped is the error returned when StrictShiftTypes is set and a rule references a shift type without mapping
+var ErrorShiftTypeUnmapped = fmt.Errorf("No mapping found for shift type")
+
+// RuleConflictError is returned by CopyRules when ConflictFail is used and a
+// rule with the same ExternalID already exists in a target
+type RuleConflictError struct {
+	Target     *RequestOptions
+	ExternalID string
+}
+
+func (e *RuleConflictError) Error() string {
+	unit, section := "", ""
+	if e.Target != nil && e.Target.ExternalUnitID != nil {
+		unit = *e.Target.ExternalUnitID
+	}
+	if e.Target != nil && e.Target.ExternalSectionID != nil {
+		section = *e.Target.ExternalSectionID
+	}
+	return fmt.Sprintf("rule %q already exists in unit %q section %q", e.ExternalID, unit, section)
+}
+
+// RuleIDNamer builds the ExternalID of a copied rule from the source rule ExternalID and the target
+type RuleIDNamer func(sourceExternalID string, target *RequestOptions) string
+
+// RuleIDTemplate returns a RuleIDNamer expanding {id}, {unit} and {section} in format.
+// {section} expands to an empty string when the target has no section.
+func RuleIDTemplate(format string) RuleIDNamer {
+	return func(sourceExternalID string, target *RequestOptions) string {
+		var unit, section string
+		if target != nil {
+			if target.ExternalUnitID != nil {
+				unit = *target.ExternalUnitID
+			}
+			if target.ExternalSectionID != nil {
+				section = *target.ExternalSectionID
+			}
+		}
+		r := strings.NewReplacer("{id}", sourceExternalID, "{unit}", unit, "{section}", section)
+		return r.Replace(format)
+	}
+}
+
+// DefaultRuleIDNamer prefixes the source ExternalID with the target unit
+var DefaultRuleIDNamer = RuleIDTemplate("{unit}-{id}")
+
+// RuleCopyOptions configures CopyRules
+type RuleCopyOptions struct {
+	// Source is the unit and section to read rules from, required
+	Source *RequestOptions
+	// Targets are the units and sections to create rules in, required
+	Targets []*RequestOptions
+	// Namer rewrites the ExternalID of every copied rule, defaults to DefaultRuleIDNamer
+	Namer RuleIDNamer
+	// ShiftTypes maps source shift type IDs to target shift type IDs.
+	// Shift types without a mapping are kept as is.
+	ShiftTypes map[string]string
+	// StrictShiftTypes makes unmapped shift types an error
+	StrictShiftTypes bool
+	// OnConflict decides what to do with rules that already exist in a target
+	OnConflict ConflictPolicy
+}
+
+// RuleCopyResult lists the ExternalIDs touched in a single target
+type RuleCopyResult struct {
+	Target  *RequestOptions
+	Created []string
+	Updated []string
+	Skipped []string
+}
+
+// CopyRules reads all dynamic and static rules of the source unit and creates
+// them under every target unit, renamed with the Namer and with shift types remapped.
+// Conflicts are resolved before any rule is written, so ConflictFail never leaves
+// a target half copied.
+func (s *ForecastService) CopyRules(ctx context.Context, opts *RuleCopyOptions) ([]*RuleCopyResult, error) {
+	if opts == nil || !opts.Source.hasRequiredFields() || len(opts.Targets) == 0 {
+		return nil, ErrorReqfieldsMissing
+	}
+	for _, t := range opts.Targets {
+		if !t.hasRequiredFields() {
+			return nil, ErrorReqfieldsMissing
+		}
+	}
+	namer := opts.Namer
+	if namer == nil {
+		namer = DefaultRuleIDNamer
+	}
+
+	dynamicRules, _, err := s.GetDynamicRules(ctx, opts.Source)
+	if err != nil {
+		return nil, err
+	}
+	staticRules, _, err := s.GetStaticRules(ctx, opts.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	plans := make([]*ruleCopyPlan, 0, len(opts.Targets))
+	for _, target := range opts.Targets {
+		p, err := s.planRuleCopy(ctx, opts, namer, target, dynamicRules, staticRules)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, p)
+	}
+
+	results := make([]*RuleCopyResult, 0, len(plans))
+	for _, p := range plans {
+		res, err := s.applyRuleCopy(ctx, p)
+		results = append(results, res)
+		if err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+type ruleCopyPlan struct {
+	target        *RequestOptions
+	dynamicRules  []*DynamicRule
+	staticRules   []*StaticRule
+	dynamicExists map[string]bool
+	staticExists  map[string]bool
+	skipped       []string
+}
+
+func (s *ForecastService) planRuleCopy(ctx context.Context, opts *RuleCopyOptions, namer RuleIDNamer, target *RequestOptions, dynamicRules []*DynamicRule, staticRules []*StaticRule) (*ruleCopyPlan, error) {
+	existingDynamic, _, err := s.GetDynamicRules(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	existingStatic, _, err := s.GetStaticRules(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	p := &ruleCopyPlan{
+		target:        target,
+		dynamicExists: make(map[string]bool, len(existingDynamic)),
+		staticExists:  make(map[string]bool, len(existingStatic)),
+	}
+	for _, r := range existingDynamic {
+		if r == nil {
+			continue
+		}
+		p.dynamicExists[r.ExternalID] = true
+	}
+	for _, r := range existingStatic {
+		if r == nil {
+			continue
+		}
+		p.staticExists[r.ExternalID] = true
+	}
+
+	for _, src := range dynamicRules {
+		if src == nil {
+			continue
+		}
+		r := *src
+		r.ExternalID = namer(src.ExternalID, target)
+		r.ShiftTypes = make([]ShiftType, len(src.ShiftTypes))
+		for i, st := range src.ShiftTypes {
+			if r.ShiftTypes[i], err = opts.mapShiftType(st); err != nil {
+				return nil, err
+			}
+		}
+		if p.dynamicExists[r.ExternalID] {
+			skip, err := opts.conflict(target, r.ExternalID)
+			if err != nil {
+				return nil, err
+			}
+			if skip {
+				p.skipped = append(p.skipped, r.ExternalID)
+				continue
+			}
+		}
+		p.dynamicRules = append(p.dynamicRules, &r)
+	}
+	for _, src := range staticRules {
+		if src == nil {
+			continue
+		}
+		r := *src
+		r.ExternalID = namer(src.ExternalID, target)
+		if r.ShiftType, err = opts.mapShiftType(src.ShiftType); err != nil {
+			return nil, err
+		}
+		if p.staticExists[r.ExternalID] {
+			skip, err := opts.conflict(target, r.ExternalID)
+			if err != nil {
+				return nil, err
+			}
+			if skip {
+				p.skipped = append(p.skipped, r.ExternalID)
+				continue
+			}
+		}
+		p.staticRules = append(p.staticRules, &r)
+	}
+	return p, nil
+}
+
+func (s *ForecastService) applyRuleCopy(ctx context.Context, p *ruleCopyPlan) (*RuleCopyResult, error) {
+	res := &RuleCopyResult{Target: p.target, Skipped: p.skipped}
+	for _, r := range p.dynamicRules {
+		if p.dynamicExists[r.ExternalID] {
+			if _, err := s.UpdateDynamicRule(ctx, r, p.target); err != nil {
+				return res, err
+			}
+			res.Updated = append(res.Updated, r.ExternalID)
+			continue
+		}
+		if _, _, err := s.CreateDynamicRule(ctx, r, p.target); err != nil {
+			return res, err
+		}
+		res.Created = append(res.Created, r.ExternalID)
+	}
+	for _, r := range p.staticRules {
+		if p.staticExists[r.ExternalID] {
+			if _, err := s.UpdateStaticRule(ctx, r, p.target); err != nil {
+				return res, err
+			}
+			res.Updated = append(res.Updated, r.ExternalID)
+			continue
+		}
+		if _, _, err := s.CreateStaticRule(ctx, r, p.target); err != nil {
+			return res, err
+		}
+		res.Created = append(res.Created, r.ExternalID)
+	}
+	return res, nil
+}
+
+func (o *RuleCopyOptions) mapShiftType(st ShiftType) (ShiftType, error) {
+	if id, ok := o.ShiftTypes[st.ShiftTypeID]; ok {
+		st.ShiftTypeID = id
+		return st, nil
+	}
+	if o.StrictShiftTypes {
+		return st, fmt.Errorf("%w: %q", ErrorShiftTypeUnmapped, st.ShiftTypeID)
+	}
+	return st, nil
+}
+
+// conflict reports whether the rule should be skipped, or an error when the policy is ConflictFail
+func (o *RuleCopyOptions) conflict(target *RequestOptions, externalID string) (bool, error) {
+	switch o.OnConflict {
+	case ConflictOverwrite:
+		return false, nil
+	case ConflictFail:
+		return false, &RuleConflictError{Target: target, ExternalID: externalID}
+	default:
+		return true, nil
+	}
+}
@@ -0,0 +1,113 @@
+package quinyx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestCopyRules(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var mu sync.Mutex
+	var created, updated []string
+	mux.HandleFunc("/forecasts/dynamic-rules", func(w http.ResponseWriter, r *http.Request) {
+		unit := r.URL.Query().Get("externalUnitId")
+		switch r.Method {
+		case "GET":
+			switch unit {
+			case "src":
+				fmt.Fprint(w, `[null,{"externalId":"morning","forecastExternalVariableId":"v","shiftTypes":[{"amount":1,"externalShiftTypeId":"old"}]}]`)
+			case "t2":
+				fmt.Fprint(w, `[{"externalId":"t2-morning"},null]`)
+			default:
+				fmt.Fprint(w, `[]`)
+			}
+		case "POST", "PUT":
+			rule := &DynamicRule{}
+			assert.NilError(t, json.NewDecoder(r.Body).Decode(rule))
+			assert.Equal(t, "new", rule.ShiftTypes[0].ShiftTypeID)
+			assert.Equal(t, "v", rule.ExternalForecastVariableID)
+			mu.Lock()
+			if r.Method == "POST" {
+				created = append(created, unit+":"+rule.ExternalID)
+			} else {
+				updated = append(updated, unit+":"+rule.ExternalID)
+			}
+			mu.Unlock()
+			json.NewEncoder(w).Encode(rule)
+		}
+	})
+	mux.HandleFunc("/forecasts/static-rules", func(w http.ResponseWriter, r *http.Request) {
+		unit := r.URL.Query().Get("externalUnitId")
+		switch r.Method {
+		case "GET":
+			if unit == "src" {
+				fmt.Fprint(w, `[null,{"externalId":"xmas","comment":"c","shiftType":{"amount":2,"externalShiftTypeId":"old"}}]`)
+				return
+			}
+			fmt.Fprint(w, `[]`)
+		case "POST":
+			rule := &StaticRule{}
+			assert.NilError(t, json.NewDecoder(r.Body).Decode(rule))
+			assert.Equal(t, "new", rule.ShiftType.ShiftTypeID)
+			mu.Lock()
+			created = append(created, unit+":"+rule.ExternalID)
+			mu.Unlock()
+			json.NewEncoder(w).Encode(rule)
+		}
+	})
+
+	opts := &RuleCopyOptions{
+		Source:     &RequestOptions{ExternalUnitID: String("src")},
+		Targets:    []*RequestOptions{{ExternalUnitID: String("t1")}, {ExternalUnitID: String("t2")}},
+		ShiftTypes: map[string]string{"old": "new"},
+	}
+
+	// Skip is the default policy
+	res, err := client.Forecast.CopyRules(context.Background(), opts)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"t1:t1-morning", "t1:t1-xmas", "t2:t2-xmas"}, created)
+	assert.DeepEqual(t, []string{"t2-morning"}, res[1].Skipped)
+
+	// Overwrite updates the existing rule
+	created = nil
+	opts.OnConflict = ConflictOverwrite
+	opts.Targets = opts.Targets[1:]
+	res, err = client.Forecast.CopyRules(context.Background(), opts)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"t2:t2-morning"}, updated)
+	assert.DeepEqual(t, []string{"t2-morning"}, res[0].Updated)
+
+	// Fail writes nothing
+	created, updated = nil, nil
+	opts.OnConflict = ConflictFail
+	_, err = client.Forecast.CopyRules(context.Background(), opts)
+	var conflict *RuleConflictError
+	assert.Assert(t, errors.As(err, &conflict))
+	assert.Equal(t, "t2-morning", conflict.ExternalID)
+	assert.Equal(t, 0, len(created)+len(updated))
+
+	// Unmapped shift types
+	opts.OnConflict = ConflictSkip
+	opts.ShiftTypes = nil
+	opts.StrictShiftTypes = true
+	_, err = client.Forecast.CopyRules(context.Background(), opts)
+	assert.Assert(t, errors.Is(err, ErrorShiftTypeUnmapped))
+
+	_, err = client.Forecast.CopyRules(context.Background(), &RuleCopyOptions{Source: opts.Source})
+	assert.ErrorContains(t, err, "Required fields in the Options")
+}
+
+func TestRuleIDTemplate(t *testing.T) {
+	namer := RuleIDTemplate("{unit}/{section}/{id}")
+	assert.Equal(t, "u/s/r", namer("r", &RequestOptions{ExternalUnitID: String("u"), ExternalSectionID: String("s")}))
+	assert.Equal(t, "u-r", DefaultRuleIDNamer("r", &RequestOptions{ExternalUnitID: String("u")}))
+}
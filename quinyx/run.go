@@ -0,0 +1,36 @@
+package quinyx
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ErrorIntervalNotPositive is returned by Run when the Interval is not set
+var ErrorIntervalNotPositive = fmt.Errorf("Interval must be positive")
+
+// runEvery calls fn right away and then every interval until ctx is done. An error
+// of fn stops it unless onErr is set, which then receives the error instead.
+func runEvery(ctx context.Context, interval time.Duration, fn func(context.Context) error, onErr func(error)) error {
+	if interval <= 0 {
+		return ErrorIntervalNotPositive
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := fn(ctx); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if onErr == nil {
+				return err
+			}
+			onErr(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
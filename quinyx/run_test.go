@@ -0,0 +1,35 @@
+package quinyx
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestRunEvery(t *testing.T) {
+	assert.Equal(t, ErrorIntervalNotPositive, (&Snapshotter{}).Run(context.Background()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	var errs []error
+	err := runEvery(ctx, time.Millisecond, func(ctx context.Context) error {
+		calls++
+		if calls == 3 {
+			cancel()
+			return ctx.Err()
+		}
+		return fmt.Errorf("call %d", calls)
+	}, func(err error) { errs = append(errs, err) })
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 2, len(errs))
+
+	err = runEvery(context.Background(), time.Millisecond, func(ctx context.Context) error {
+		return fmt.Errorf("stop")
+	}, nil)
+	assert.Error(t, err, "stop")
+}
@@ -0,0 +1,400 @@
+package quinyx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrorRunNotFound is the error returned when a prediction run is not in the RunStore
+var ErrorRunNotFound = fmt.Errorf("Prediction run not found")
+
+// PredictionRun is a recorded upload of predicted data
+type PredictionRun struct {
+	RunIdentifier string               `json:"runIdentifier"`
+	RunTimestamp  time.Time            `json:"runTimestamp"`
+	RecordedAt    time.Time            `json:"recordedAt"`
+	Variables     []string             `json:"variables"`
+	Units         []string             `json:"units"`
+	Windows       []*RunWindow         `json:"windows"`
+	Checksum      string               `json:"checksum"`
+	Predictions   []ForecastPrediction `json:"predictions,omitempty"`
+}
+
+// RunWindow is the time span covered by a run for a single variable, unit and section
+type RunWindow struct {
+	ExternalForecastVariableID      string    `json:"variable"`
+	ExternalForecastConfigurationID string    `json:"configuration,omitempty"`
+	ExternalUnitID                  string    `json:"unit"`
+	ExternalSectionID               *string   `json:"section,omitempty"`
+	StartTime                       time.Time `json:"startTime"`
+	EndTime                         time.Time `json:"endTime"`
+	Rows                            int       `json:"rows"`
+	Checksum                        string    `json:"checksum"`
+}
+
+// RunStore persists prediction runs
+type RunStore interface {
+	SaveRun(run *PredictionRun) error
+	// LoadRun returns ErrorRunNotFound for unknown runs
+	LoadRun(runIdentifier string) (*PredictionRun, error)
+	ListRuns() ([]*PredictionRun, error)
+}
+
+// FileRunStore is a RunStore keeping one JSON file per run in Dir
+type FileRunStore struct {
+	Dir string
+}
+
+func (s *FileRunStore) path(runIdentifier string) string {
+	return filepath.Join(s.Dir, url.PathEscape(runIdentifier)+".json")
+}
+
+// SaveRun writes the run to its file, replacing any earlier version
+func (s *FileRunStore) SaveRun(run *PredictionRun) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write to a temporary file first so a crash never leaves a truncated run behind
+	tmp := s.path(run.RunIdentifier) + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(run.RunIdentifier))
+}
+
+// LoadRun reads a single run including its rows
+func (s *FileRunStore) LoadRun(runIdentifier string) (*PredictionRun, error) {
+	b, err := ioutil.ReadFile(s.path(runIdentifier))
+	if os.IsNotExist(err) {
+		return nil, ErrorRunNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	run := &PredictionRun{}
+	if err := json.Unmarshal(b, run); err != nil {
+		return nil, fmt.Errorf("%s: %v", s.path(runIdentifier), err)
+	}
+	return run, nil
+}
+
+// ListRuns returns every run in Dir without rows, oldest first
+func (s *FileRunStore) ListRuns() ([]*PredictionRun, error) {
+	files, err := filepath.Glob(filepath.Join(s.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	runs := make([]*PredictionRun, 0, len(files))
+	for _, f := range files {
+		id, err := url.PathUnescape(strings.TrimSuffix(filepath.Base(f), ".json"))
+		if err != nil {
+			continue
+		}
+		run, err := s.LoadRun(id)
+		if err != nil {
+			return nil, err
+		}
+		run.Predictions = nil
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].RecordedAt.Before(runs[j].RecordedAt) })
+	return runs, nil
+}
+
+// RunManager uploads predicted data and keeps track of every run in a RunStore
+type RunManager struct {
+	Forecast *ForecastService
+	Store    RunStore
+}
+
+// Upload sends inlist with UploadPredictedData and records it as a run. Predictions
+// without RunIdentifier or RunTimestamp get the ones of the first prediction, or a
+// generated identifier and the current time.
+func (m *RunManager) Upload(ctx context.Context, inlist *PredictedDataInputList) (*PredictionRun, *Response, error) {
+	if inlist == nil || len(inlist.ForecastPredictions) == 0 {
+		return nil, nil, fmt.Errorf("no predictions to upload")
+	}
+	now := time.Now().UTC()
+	first := inlist.ForecastPredictions[0]
+	runID := "run-" + now.Format("20060102T150405.000000000")
+	if first.RunIdentifier != nil {
+		runID = *first.RunIdentifier
+	}
+	runTS := now
+	if first.RunTimestamp != nil {
+		runTS = first.RunTimestamp.Time
+	}
+	for i := range inlist.ForecastPredictions {
+		fp := &inlist.ForecastPredictions[i]
+		if fp.RunIdentifier == nil {
+			fp.RunIdentifier = String(runID)
+		}
+		if fp.RunTimestamp == nil {
+			fp.RunTimestamp = &Timestamp{runTS}
+		}
+	}
+
+	resp, err := m.Forecast.UploadPredictedData(ctx, inlist)
+	if err != nil {
+		return nil, resp, err
+	}
+	run := newPredictionRun(runID, runTS, inlist.ForecastPredictions)
+	run.RecordedAt = now
+	if err := m.Store.SaveRun(run); err != nil {
+		return run, resp, err
+	}
+	return run, resp, nil
+}
+
+// List returns all recorded runs, oldest first
+func (m *RunManager) List() ([]*PredictionRun, error) {
+	return m.Store.ListRuns()
+}
+
+// Diff compares the rows of two recorded runs
+func (m *RunManager) Diff(fromRunIdentifier, toRunIdentifier string) (*RunDiff, error) {
+	from, err := m.Store.LoadRun(fromRunIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	to, err := m.Store.LoadRun(toRunIdentifier)
+	if err != nil {
+		return nil, err
+	}
+	return DiffRuns(from, to), nil
+}
+
+// Rollback restores a recorded run. The forecast data of every window of the run is
+// deleted with DeleteForecastData and the stored rows are uploaded again.
+func (m *RunManager) Rollback(ctx context.Context, runIdentifier string) error {
+	run, err := m.Store.LoadRun(runIdentifier)
+	if err != nil {
+		return err
+	}
+	for _, w := range run.Windows {
+		if w.StartTime.IsZero() || w.EndTime.IsZero() {
+			// None of the rows had a timestamp, so there is nothing to delete
+			continue
+		}
+		opts := &RequestRangeOptions{
+			StartTime:         w.StartTime,
+			EndTime:           w.EndTime,
+			ExternalUnitID:    String(w.ExternalUnitID),
+			ExternalSectionID: w.ExternalSectionID,
+		}
+		for _, chunk := range opts.chunks() {
+			if _, err := m.Forecast.DeleteForecastData(ctx, w.ExternalForecastVariableID, chunk); err != nil {
+				return err
+			}
+		}
+	}
+	for start := 0; start < len(run.Predictions); start += maxRowsPerCall {
+		end := start + maxRowsPerCall
+		if end > len(run.Predictions) {
+			end = len(run.Predictions)
+		}
+		if _, err := m.Forecast.UploadPredictedData(ctx, &PredictedDataInputList{ForecastPredictions: run.Predictions[start:end]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newPredictionRun(runID string, runTS time.Time, predictions []ForecastPrediction) *PredictionRun {
+	run := &PredictionRun{
+		RunIdentifier: runID,
+		RunTimestamp:  runTS,
+		Predictions:   predictions,
+	}
+	variables := map[string]bool{}
+	units := map[string]bool{}
+	sum := sha256.New()
+	// Predictions of the same series share a window, in the order they first appear
+	hashes := map[runSeriesKey]hash.Hash{}
+	byKey := map[runSeriesKey]*RunWindow{}
+	for _, fp := range predictions {
+		k := runSeriesKey{stringValue(fp.ExternalForecastVariableID), stringValue(fp.ExternalForecastConfigurationID), stringValue(fp.ExternalUnitID), stringValue(fp.ExternalSectionID)}
+		w, ok := byKey[k]
+		if !ok {
+			w = &RunWindow{
+				ExternalForecastVariableID:      k.variable,
+				ExternalForecastConfigurationID: k.configuration,
+				ExternalUnitID:                  k.unit,
+				ExternalSectionID:               fp.ExternalSectionID,
+			}
+			byKey[k] = w
+			hashes[k] = sha256.New()
+			run.Windows = append(run.Windows, w)
+		}
+		w.Rows += len(fp.Payloads)
+		variables[w.ExternalForecastVariableID] = true
+		units[w.ExternalUnitID] = true
+		h := hashes[k]
+		for _, p := range fp.Payloads {
+			if p == nil || p.Timestamp == nil {
+				continue
+			}
+			ts := p.Timestamp.Time.UTC()
+			if w.StartTime.IsZero() || ts.Before(w.StartTime) {
+				w.StartTime = ts
+			}
+			if ts.After(w.EndTime) {
+				w.EndTime = ts
+			}
+			fmt.Fprintf(h, "%d|%s\n", ts.UnixNano(), formatFloat(p.Data))
+		}
+	}
+	for _, w := range run.Windows {
+		k := runSeriesKey{w.ExternalForecastVariableID, w.ExternalForecastConfigurationID, w.ExternalUnitID, stringValue(w.ExternalSectionID)}
+		// DeleteForecastData needs whole hours, the end is moved past the last row.
+		// A window without any timestamp keeps zero times and is skipped by Rollback.
+		if !w.StartTime.IsZero() {
+			w.StartTime = w.StartTime.Truncate(time.Hour)
+			w.EndTime = w.EndTime.Truncate(time.Hour).Add(time.Hour)
+		}
+		w.Checksum = hex.EncodeToString(hashes[k].Sum(nil))
+		fmt.Fprintf(sum, "%s|%s|%s|%s|%s\n", w.ExternalForecastVariableID, w.ExternalForecastConfigurationID, w.ExternalUnitID, stringValue(w.ExternalSectionID), w.Checksum)
+	}
+	run.Checksum = hex.EncodeToString(sum.Sum(nil))
+	run.Variables = sortedKeys(variables)
+	run.Units = sortedKeys(units)
+	return run
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// RunDiff is the difference between the rows of two runs
+type RunDiff struct {
+	From, To string
+	// Added are the series only present in To
+	Added []*RunWindow
+	// Removed are the series only present in From
+	Removed []*RunWindow
+	// Changed are the rows whose value differ, or that only exist in one of the runs, in series present in both
+	Changed []*RunValueChange
+}
+
+// RunValueChange is a single row that differs between two runs
+type RunValueChange struct {
+	ExternalForecastVariableID string
+	ExternalUnitID             string
+	ExternalSectionID          *string
+	Timestamp                  time.Time
+	From, To                   *float64
+}
+
+type runSeriesKey struct {
+	variable, configuration, unit, section string
+}
+
+// DiffRuns compares the rows of two runs. Both runs must include their predictions.
+func DiffRuns(from, to *PredictionRun) *RunDiff {
+	d := &RunDiff{From: from.RunIdentifier, To: to.RunIdentifier}
+	fromSeries := runSeries(from)
+	toSeries := runSeries(to)
+	windows := func(run *PredictionRun) map[runSeriesKey]*RunWindow {
+		m := map[runSeriesKey]*RunWindow{}
+		for _, w := range run.Windows {
+			m[runSeriesKey{w.ExternalForecastVariableID, w.ExternalForecastConfigurationID, w.ExternalUnitID, stringValue(w.ExternalSectionID)}] = w
+		}
+		return m
+	}
+	fromWindows, toWindows := windows(from), windows(to)
+
+	for _, w := range to.Windows {
+		k := runSeriesKey{w.ExternalForecastVariableID, w.ExternalForecastConfigurationID, w.ExternalUnitID, stringValue(w.ExternalSectionID)}
+		if _, ok := fromWindows[k]; !ok {
+			d.Added = append(d.Added, w)
+		}
+	}
+	for _, w := range from.Windows {
+		k := runSeriesKey{w.ExternalForecastVariableID, w.ExternalForecastConfigurationID, w.ExternalUnitID, stringValue(w.ExternalSectionID)}
+		tw, ok := toWindows[k]
+		if !ok {
+			d.Removed = append(d.Removed, w)
+			continue
+		}
+		if tw.Checksum == w.Checksum {
+			continue
+		}
+		a, b := fromSeries[k], toSeries[k]
+		var stamps []int64
+		for ts := range a {
+			stamps = append(stamps, ts)
+		}
+		for ts := range b {
+			if _, ok := a[ts]; !ok {
+				stamps = append(stamps, ts)
+			}
+		}
+		sort.Slice(stamps, func(i, j int) bool { return stamps[i] < stamps[j] })
+		for _, ts := range stamps {
+			av, aok := a[ts]
+			bv, bok := b[ts]
+			if aok && bok && formatFloat(av) == formatFloat(bv) {
+				continue
+			}
+			d.Changed = append(d.Changed, &RunValueChange{
+				ExternalForecastVariableID: w.ExternalForecastVariableID,
+				ExternalUnitID:             w.ExternalUnitID,
+				ExternalSectionID:          w.ExternalSectionID,
+				Timestamp:                  time.Unix(0, ts).UTC(),
+				From:                       av,
+				To:                         bv,
+			})
+		}
+	}
+	return d
+}
+
+func runSeries(run *PredictionRun) map[runSeriesKey]map[int64]*float64 {
+	series := map[runSeriesKey]map[int64]*float64{}
+	for _, fp := range run.Predictions {
+		k := runSeriesKey{stringValue(fp.ExternalForecastVariableID), stringValue(fp.ExternalForecastConfigurationID), stringValue(fp.ExternalUnitID), stringValue(fp.ExternalSectionID)}
+		if series[k] == nil {
+			series[k] = map[int64]*float64{}
+		}
+		for _, p := range fp.Payloads {
+			if p != nil && p.Timestamp != nil {
+				series[k][p.Timestamp.Time.UnixNano()] = p.Data
+			}
+		}
+	}
+	return series
+}
+
+func (c *RunValueChange) String() string {
+	section := ""
+	if c.ExternalSectionID != nil {
+		section = "/" + *c.ExternalSectionID
+	}
+	value := func(f *float64) string {
+		if f == nil {
+			return "-"
+		}
+		return formatFloat(f)
+	}
+	return fmt.Sprintf("%s %s%s %s: %s -> %s", c.ExternalForecastVariableID, c.ExternalUnitID, section, c.Timestamp.Format(time.RFC3339), value(c.From), value(c.To))
+}
@@ -0,0 +1,138 @@
+package quinyx
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func testPredictions(runID string, values ...float64) *PredictedDataInputList {
+	fp := ForecastPrediction{
+		ExternalForecastVariableID:      String("v"),
+		ExternalForecastConfigurationID: String("cfg"),
+		ExternalUnitID:                  String("u"),
+		RunIdentifier:                   String(runID),
+	}
+	for i, v := range values {
+		fp.Payloads = append(fp.Payloads, &Payload{
+			Data:      Float64(v),
+			Timestamp: &Timestamp{time.Date(2020, time.October, 12, 8, 15*i, 0, 0, time.UTC)},
+		})
+	}
+	return &PredictedDataInputList{ForecastPredictions: []ForecastPrediction{fp}}
+}
+
+func TestRunManager(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var uploads []*PredictedDataInputList
+	mux.HandleFunc("/forecasts/predicted-data", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NilError(t, err)
+		in := &PredictedDataInputList{}
+		assert.NilError(t, json.Unmarshal(body, in))
+		uploads = append(uploads, in)
+	})
+	var deletes []string
+	mux.HandleFunc("/forecasts/forecast-variables/v/forecast-data", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "DELETE")
+		q := r.URL.Query()
+		deletes = append(deletes, q.Get("externalUnitId")+" "+q.Get("startTime")+" "+q.Get("endTime"))
+	})
+
+	dir, err := ioutil.TempDir("", "runs")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	m := &RunManager{Forecast: client.Forecast, Store: &FileRunStore{Dir: dir}}
+
+	first, _, err := m.Upload(context.Background(), testPredictions("r1", 1, 2, 3))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"v"}, first.Variables)
+	assert.Equal(t, 3, first.Windows[0].Rows)
+	assert.Equal(t, time.Date(2020, time.October, 12, 8, 0, 0, 0, time.UTC), first.Windows[0].StartTime)
+	assert.Equal(t, time.Date(2020, time.October, 12, 9, 0, 0, 0, time.UTC), first.Windows[0].EndTime)
+
+	second, _, err := m.Upload(context.Background(), testPredictions("r2", 1, 5))
+	assert.NilError(t, err)
+	assert.Assert(t, first.Checksum != second.Checksum)
+
+	runs, err := m.List()
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(runs))
+	assert.Equal(t, "r1", runs[0].RunIdentifier)
+	assert.Equal(t, 0, len(runs[0].Predictions))
+
+	diff, err := m.Diff("r1", "r2")
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(diff.Changed))
+	assert.Equal(t, "v u 2020-10-12T08:15:00Z: 2 -> 5", diff.Changed[0].String())
+	assert.Equal(t, "v u 2020-10-12T08:30:00Z: 3 -> -", diff.Changed[1].String())
+
+	uploads = nil
+	assert.NilError(t, m.Rollback(context.Background(), "r1"))
+	assert.DeepEqual(t, []string{"u 2020-10-12T08:00:00Z 2020-10-12T09:00:00Z"}, deletes)
+	assert.Equal(t, 1, len(uploads))
+	assert.Equal(t, "r1", *uploads[0].ForecastPredictions[0].RunIdentifier)
+	assert.Equal(t, 3, len(uploads[0].ForecastPredictions[0].Payloads))
+
+	assert.Equal(t, ErrorRunNotFound, m.Rollback(context.Background(), "nope"))
+}
+
+func TestRunManagerWindows(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/forecasts/predicted-data", func(w http.ResponseWriter, r *http.Request) {})
+	var deletes []string
+	mux.HandleFunc("/forecasts/forecast-variables/v/forecast-data", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		deletes = append(deletes, q.Get("externalUnitId")+" "+q.Get("startTime")+" "+q.Get("endTime"))
+	})
+
+	dir, err := ioutil.TempDir("", "runs")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	m := &RunManager{Forecast: client.Forecast, Store: &FileRunStore{Dir: dir}}
+
+	in := testPredictions("r1", 1, 2)
+	later := testPredictions("r1", 3).ForecastPredictions[0]
+	later.Payloads[0].Timestamp = &Timestamp{time.Date(2020, time.October, 12, 11, 0, 0, 0, time.UTC)}
+	untimed := testPredictions("r1", 4).ForecastPredictions[0]
+	untimed.ExternalUnitID = String("w")
+	untimed.Payloads[0].Timestamp = nil
+	in.ForecastPredictions = append(in.ForecastPredictions, later, untimed)
+
+	run, _, err := m.Upload(context.Background(), in)
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(run.Windows))
+	assert.Equal(t, 3, run.Windows[0].Rows)
+	assert.Equal(t, time.Date(2020, time.October, 12, 8, 0, 0, 0, time.UTC), run.Windows[0].StartTime)
+	assert.Equal(t, time.Date(2020, time.October, 12, 12, 0, 0, 0, time.UTC), run.Windows[0].EndTime)
+	assert.Assert(t, run.Windows[1].StartTime.IsZero())
+
+	assert.NilError(t, m.Rollback(context.Background(), "r1"))
+	assert.DeepEqual(t, []string{"u 2020-10-12T08:00:00Z 2020-10-12T12:00:00Z"}, deletes)
+}
+
+func TestRangeChunks(t *testing.T) {
+	opts := &RequestRangeOptions{
+		StartTime:      time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		EndTime:        time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+		ExternalUnitID: String("u"),
+	}
+	chunks := opts.chunks()
+	assert.Equal(t, 4, len(chunks))
+	for _, c := range chunks {
+		assert.Assert(t, c.dayDistance() <= maxDaysRange)
+		assert.Equal(t, "u", *c.ExternalUnitID)
+	}
+	assert.Equal(t, opts.EndTime, chunks[3].EndTime)
+}
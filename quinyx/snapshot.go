@@ -0,0 +1,338 @@
+package quinyx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrorSnapshotNotFound is the error returned when a snapshot is not in the SnapshotStore
+var ErrorSnapshotNotFound = fmt.Errorf("Snapshot not found")
+
+// ErrorSnapshotIDInvalid is returned by FileSnapshotStore.SaveSnapshot for an ID that is
+// not a key directory and a file name, such as one with a ".." segment
+var ErrorSnapshotIDInvalid = fmt.Errorf("Snapshot ID is not a valid path")
+
+// snapshotIDTime is the time format of the time part of a snapshot ID
+const snapshotIDTime = "20060102T150405.000000000Z"
+
+// SnapshotKey identifies the calculated forecast a snapshot was taken of
+type SnapshotKey struct {
+	ExternalForecastVariableID      string `json:"variable"`
+	ExternalForecastConfigurationID string `json:"configuration"`
+	ExternalUnitID                  string `json:"unit"`
+	ExternalSectionID               string `json:"section,omitempty"`
+}
+
+func (k SnapshotKey) String() string {
+	return strings.Join([]string{k.ExternalForecastVariableID, k.ExternalForecastConfigurationID, k.ExternalUnitID, k.ExternalSectionID}, "|")
+}
+
+// ForecastSnapshot is a calculated forecast as it looked at TakenAt
+type ForecastSnapshot struct {
+	ID        string               `json:"id"`
+	Key       SnapshotKey          `json:"key"`
+	TakenAt   time.Time            `json:"takenAt"`
+	StartTime time.Time            `json:"startTime"`
+	EndTime   time.Time            `json:"endTime"`
+	Payloads  []*CalculatedPayload `json:"payloads,omitempty"`
+}
+
+// SnapshotStore persists snapshots
+type SnapshotStore interface {
+	SaveSnapshot(snapshot *ForecastSnapshot) error
+	// LoadSnapshot returns ErrorSnapshotNotFound for unknown IDs
+	LoadSnapshot(id string) (*ForecastSnapshot, error)
+	// ListSnapshots returns the snapshots of key without payloads, oldest first
+	ListSnapshots(key SnapshotKey) ([]*ForecastSnapshot, error)
+}
+
+// MemorySnapshotStore is a SnapshotStore kept in memory
+type MemorySnapshotStore struct {
+	mu        sync.RWMutex
+	snapshots map[string]*ForecastSnapshot
+}
+
+// NewMemorySnapshotStore returns an empty MemorySnapshotStore
+func NewMemorySnapshotStore() *MemorySnapshotStore {
+	return &MemorySnapshotStore{snapshots: map[string]*ForecastSnapshot{}}
+}
+
+// SaveSnapshot stores snapshot
+func (s *MemorySnapshotStore) SaveSnapshot(snapshot *ForecastSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshots[snapshot.ID] = snapshot
+	return nil
+}
+
+// LoadSnapshot returns the snapshot with id
+func (s *MemorySnapshotStore) LoadSnapshot(id string) (*ForecastSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snap, ok := s.snapshots[id]
+	if !ok {
+		return nil, ErrorSnapshotNotFound
+	}
+	return snap, nil
+}
+
+// ListSnapshots returns the snapshots of key, oldest first
+func (s *MemorySnapshotStore) ListSnapshots(key SnapshotKey) ([]*ForecastSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var list []*ForecastSnapshot
+	for _, snap := range s.snapshots {
+		if snap.Key == key {
+			c := *snap
+			c.Payloads = nil
+			list = append(list, &c)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].TakenAt.Before(list[j].TakenAt) })
+	return list, nil
+}
+
+// FileSnapshotStore is a SnapshotStore keeping a directory per key in Dir and a JSON file per snapshot
+type FileSnapshotStore struct {
+	Dir string
+}
+
+func (s *FileSnapshotStore) keyDir(key SnapshotKey) string {
+	return url.PathEscape(key.String())
+}
+
+// validID reports whether id is a key directory and a file name, so that it cannot
+// point outside Dir. Dots are fine within a segment, as in the unit ID a..b.
+func (s *FileSnapshotStore) validID(id string) bool {
+	segments := strings.Split(id, "/")
+	if len(segments) != 2 {
+		return false
+	}
+	for _, seg := range segments {
+		if seg == "" || seg == "." || seg == ".." || strings.Contains(seg, `\`) {
+			return false
+		}
+	}
+	return true
+}
+
+// SaveSnapshot writes snapshot to its file
+func (s *FileSnapshotStore) SaveSnapshot(snapshot *ForecastSnapshot) error {
+	if !s.validID(snapshot.ID) {
+		return ErrorSnapshotIDInvalid
+	}
+	dir := filepath.Join(s.Dir, s.keyDir(snapshot.Key))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(s.Dir, filepath.FromSlash(snapshot.ID)+".json")
+	if err := ioutil.WriteFile(path+".tmp", b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// LoadSnapshot reads the snapshot with id
+func (s *FileSnapshotStore) LoadSnapshot(id string) (*ForecastSnapshot, error) {
+	if !s.validID(id) {
+		return nil, ErrorSnapshotNotFound
+	}
+	b, err := ioutil.ReadFile(filepath.Join(s.Dir, filepath.FromSlash(id)+".json"))
+	if os.IsNotExist(err) {
+		return nil, ErrorSnapshotNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	snap := &ForecastSnapshot{}
+	if err := json.Unmarshal(b, snap); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %v", id, err)
+	}
+	return snap, nil
+}
+
+// ListSnapshots returns the snapshots of key without payloads, oldest first
+func (s *FileSnapshotStore) ListSnapshots(key SnapshotKey) ([]*ForecastSnapshot, error) {
+	files, err := filepath.Glob(filepath.Join(s.Dir, s.keyDir(key), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*ForecastSnapshot, 0, len(files))
+	for _, f := range files {
+		snap, err := s.LoadSnapshot(s.keyDir(key) + "/" + strings.TrimSuffix(filepath.Base(f), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		snap.Payloads = nil
+		list = append(list, snap)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].TakenAt.Before(list[j].TakenAt) })
+	return list, nil
+}
+
+// SnapshotTarget is a forecast variable in a unit to take snapshots of
+type SnapshotTarget struct {
+	ExternalForecastVariableID string
+	// ExternalForecastConfigurationID limits the snapshots to one configuration, all are taken when empty
+	ExternalForecastConfigurationID string
+	Options                         *RequestOptions
+}
+
+// Snapshotter periodically stores the calculated forecast of its targets
+type Snapshotter struct {
+	Forecast *ForecastService
+	Store    SnapshotStore
+	Targets  []*SnapshotTarget
+	// Window returns the range to snapshot, defaults to the 28 days starting today in UTC
+	Window func(now time.Time) (start, end time.Time)
+	// Interval between snapshots taken by Run
+	Interval time.Duration
+	// OnError, if set, receives the errors of Run instead of stopping it
+	OnError func(error)
+}
+
+// Snapshot takes and stores one snapshot per target, configuration and unit
+func (s *Snapshotter) Snapshot(ctx context.Context) ([]*ForecastSnapshot, error) {
+	now := time.Now().UTC()
+	start, end := s.window(now)
+	var snaps []*ForecastSnapshot
+	for _, t := range s.Targets {
+		opts := &RequestRangeOptions{StartTime: start, EndTime: end}
+		if t.Options != nil {
+			opts.ExternalUnitID = t.Options.ExternalUnitID
+			opts.ExternalSectionID = t.Options.ExternalSectionID
+		}
+		if !opts.hasRequiredFields() {
+			return snaps, ErrorReqfieldsMissing
+		}
+		byKey := map[SnapshotKey]*ForecastSnapshot{}
+		var keys []SnapshotKey
+		for _, chunk := range opts.chunks() {
+			forecasts, _, err := s.Forecast.GetCalculatedForecast(ctx, t.ExternalForecastVariableID, chunk)
+			if err != nil {
+				return snaps, err
+			}
+			for _, cf := range forecasts {
+				key := SnapshotKey{
+					ExternalForecastVariableID:      t.ExternalForecastVariableID,
+					ExternalForecastConfigurationID: stringValue(cf.ExternalForecastConfigurationID),
+					ExternalUnitID:                  stringValue(cf.ExternalUnitID),
+					ExternalSectionID:               stringValue(cf.ExternalSectionID),
+				}
+				if t.ExternalForecastConfigurationID != "" && key.ExternalForecastConfigurationID != t.ExternalForecastConfigurationID {
+					continue
+				}
+				if key.ExternalUnitID == "" {
+					key.ExternalUnitID = *opts.ExternalUnitID
+				}
+				snap, ok := byKey[key]
+				if !ok {
+					snap = &ForecastSnapshot{
+						ID:        url.PathEscape(key.String()) + "/" + now.Format(snapshotIDTime),
+						Key:       key,
+						TakenAt:   now,
+						StartTime: start,
+						EndTime:   end,
+					}
+					byKey[key] = snap
+					keys = append(keys, key)
+				}
+				snap.Payloads = append(snap.Payloads, cf.DataPayload...)
+			}
+		}
+		for _, key := range keys {
+			if err := s.Store.SaveSnapshot(byKey[key]); err != nil {
+				return snaps, err
+			}
+			snaps = append(snaps, byKey[key])
+		}
+	}
+	return snaps, nil
+}
+
+// Run takes a snapshot right away and then every Interval until ctx is done
+func (s *Snapshotter) Run(ctx context.Context) error {
+	return runEvery(ctx, s.Interval, func(ctx context.Context) error {
+		_, err := s.Snapshot(ctx)
+		return err
+	}, s.OnError)
+}
+
+func (s *Snapshotter) window(now time.Time) (time.Time, time.Time) {
+	if s.Window != nil {
+		return s.Window(now)
+	}
+	start := now.Truncate(24 * time.Hour)
+	return start, start.AddDate(0, 0, 28)
+}
+
+// SnapshotSlotDiff is the change of a single time slot between two snapshots
+type SnapshotSlotDiff struct {
+	StartTime  time.Time
+	EndTime    time.Time
+	FromData   *float64
+	ToData     *float64
+	FromEdited *float64
+	ToEdited   *float64
+}
+
+// DataChanged reports whether the calculated value changed
+func (d *SnapshotSlotDiff) DataChanged() bool {
+	return formatFloat(d.FromData) != formatFloat(d.ToData)
+}
+
+// EditedChanged reports whether the manually edited value changed
+func (d *SnapshotSlotDiff) EditedChanged() bool {
+	return formatFloat(d.FromEdited) != formatFloat(d.ToEdited)
+}
+
+// DiffSnapshots returns the time slots whose base or edited value differ between
+// from and to, including slots only present in one of them, ordered by StartTime.
+func DiffSnapshots(from, to *ForecastSnapshot) []*SnapshotSlotDiff {
+	slots := map[int64]*SnapshotSlotDiff{}
+	slot := func(p *CalculatedPayload) *SnapshotSlotDiff {
+		k := p.StartTime.Time.UnixNano()
+		d, ok := slots[k]
+		if !ok {
+			d = &SnapshotSlotDiff{StartTime: p.StartTime.Time.UTC()}
+			if p.EndTime != nil {
+				d.EndTime = p.EndTime.Time.UTC()
+			}
+			slots[k] = d
+		}
+		return d
+	}
+	for _, p := range from.Payloads {
+		if p != nil && p.StartTime != nil {
+			d := slot(p)
+			d.FromData, d.FromEdited = p.Data, p.EditedData
+		}
+	}
+	for _, p := range to.Payloads {
+		if p != nil && p.StartTime != nil {
+			d := slot(p)
+			d.ToData, d.ToEdited = p.Data, p.EditedData
+		}
+	}
+	var diffs []*SnapshotSlotDiff
+	for _, d := range slots {
+		if d.DataChanged() || d.EditedChanged() {
+			diffs = append(diffs, d)
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].StartTime.Before(diffs[j].StartTime) })
+	return diffs
+}
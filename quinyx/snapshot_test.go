@@ -0,0 +1,103 @@
+package quinyx
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestSnapshotter(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	responses := []string{
+		`[{"externalForecastConfigurationId":"cfg","externalUnitId":"u","dataPayload":[
+			{"data":10,"startTime":"2020-10-12T08:00:00Z","endTime":"2020-10-12T09:00:00Z"},
+			{"data":20,"startTime":"2020-10-12T09:00:00Z","endTime":"2020-10-12T10:00:00Z"}]},
+		  {"externalForecastConfigurationId":"other","externalUnitId":"u","dataPayload":[]}]`,
+		`[{"externalForecastConfigurationId":"cfg","externalUnitId":"u","dataPayload":[
+			{"data":10,"editedData":12,"startTime":"2020-10-12T08:00:00Z","endTime":"2020-10-12T09:00:00Z"},
+			{"data":20,"startTime":"2020-10-12T09:00:00Z","endTime":"2020-10-12T10:00:00Z"},
+			{"data":5,"startTime":"2020-10-12T10:00:00Z","endTime":"2020-10-12T11:00:00Z"}]}]`,
+	}
+	calls := 0
+	mux.HandleFunc("/forecasts/forecast-variables/sales/calculated-forecast", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		assert.Equal(t, "u", r.URL.Query().Get("externalUnitId"))
+		fmt.Fprint(w, responses[calls])
+		calls++
+	})
+
+	dir, err := ioutil.TempDir("", "snapshots")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, store := range []SnapshotStore{NewMemorySnapshotStore(), &FileSnapshotStore{Dir: dir}} {
+		calls = 0
+		s := &Snapshotter{
+			Forecast: client.Forecast,
+			Store:    store,
+			Targets: []*SnapshotTarget{{
+				ExternalForecastVariableID:      "sales",
+				ExternalForecastConfigurationID: "cfg",
+				Options:                         &RequestOptions{ExternalUnitID: String("u")},
+			}},
+			Window: func(now time.Time) (time.Time, time.Time) {
+				return time.Date(2020, time.October, 12, 0, 0, 0, 0, time.UTC), time.Date(2020, time.October, 13, 0, 0, 0, 0, time.UTC)
+			},
+		}
+		first, err := s.Snapshot(context.Background())
+		assert.NilError(t, err)
+		assert.Equal(t, 1, len(first))
+		second, err := s.Snapshot(context.Background())
+		assert.NilError(t, err)
+
+		key := SnapshotKey{ExternalForecastVariableID: "sales", ExternalForecastConfigurationID: "cfg", ExternalUnitID: "u"}
+		list, err := store.ListSnapshots(key)
+		assert.NilError(t, err)
+		assert.Equal(t, 2, len(list))
+		assert.Equal(t, first[0].ID, list[0].ID)
+		assert.Equal(t, 0, len(list[0].Payloads))
+
+		a, err := store.LoadSnapshot(list[0].ID)
+		assert.NilError(t, err)
+		b, err := store.LoadSnapshot(second[0].ID)
+		assert.NilError(t, err)
+		diffs := DiffSnapshots(a, b)
+		assert.Equal(t, 2, len(diffs))
+		assert.Equal(t, time.Date(2020, time.October, 12, 8, 0, 0, 0, time.UTC), diffs[0].StartTime)
+		assert.Assert(t, diffs[0].EditedChanged() && !diffs[0].DataChanged())
+		assert.Equal(t, float64(12), *diffs[0].ToEdited)
+		assert.Assert(t, diffs[1].FromData == nil)
+		assert.Equal(t, float64(5), *diffs[1].ToData)
+
+		_, err = store.LoadSnapshot("missing/id")
+		assert.Equal(t, ErrorSnapshotNotFound, err)
+	}
+}
+
+func TestFileSnapshotStoreIDs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshots")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	store := &FileSnapshotStore{Dir: dir}
+
+	key := SnapshotKey{ExternalForecastVariableID: "v", ExternalForecastConfigurationID: "cfg", ExternalUnitID: "a..b"}
+	snap := &ForecastSnapshot{ID: store.keyDir(key) + "/t", Key: key}
+	assert.NilError(t, store.SaveSnapshot(snap))
+	got, err := store.LoadSnapshot(snap.ID)
+	assert.NilError(t, err)
+	assert.Equal(t, key, got.Key)
+
+	for _, id := range []string{"../t", "x/../../t", "x/..", "t", "x/", `x/..\t`} {
+		assert.Equal(t, ErrorSnapshotIDInvalid, store.SaveSnapshot(&ForecastSnapshot{ID: id}), id)
+		_, err = store.LoadSnapshot(id)
+		assert.Equal(t, ErrorSnapshotNotFound, err, id)
+	}
+}
@@ -0,0 +1,187 @@
+package quinyx
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+const defaultStreamInFlight = 4
+
+// maxStreamPayloads bounds the payloads held in a single batch. Quinyx limits the
+// inputs of a call, not their payloads, so this only keeps the memory flat when
+// a long series fills a batch on its own.
+const maxStreamPayloads = maxRowsPerCall * 96
+
+// StreamUploadOptions configures the streaming uploads
+type StreamUploadOptions struct {
+	// InFlight is the maximum number of upload calls running at once, defaults to 4.
+	// At most InFlight+1 batches of rows are held in memory.
+	InFlight int
+	// AppendData is passed on to UploadBudgetData
+	AppendData bool
+}
+
+// StreamUploadResult summarizes a streaming upload
+type StreamUploadResult struct {
+	// Rows is the number of rows in successful calls
+	Rows int
+	// Calls is the number of successful calls
+	Calls int
+}
+
+// ChanRowIterator returns a ForecastRowIterator reading from ch until it is closed
+func ChanRowIterator(ch <-chan *ForecastRow) ForecastRowIterator {
+	return chanRowIterator(ch)
+}
+
+type chanRowIterator <-chan *ForecastRow
+
+func (c chanRowIterator) Next() (*ForecastRow, error) {
+	return c.nextContext(context.Background())
+}
+
+func (c chanRowIterator) nextContext(ctx context.Context) (*ForecastRow, error) {
+	select {
+	case row, ok := <-c:
+		if !ok {
+			return nil, io.EOF
+		}
+		return row, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// contextRowIterator is implemented by iterators whose Next can block, streamUpload
+// then stops waiting for a row when the upload is canceled
+type contextRowIterator interface {
+	nextContext(ctx context.Context) (*ForecastRow, error)
+}
+
+type sliceRowIterator []*ForecastRow
+
+func (s *sliceRowIterator) Next() (*ForecastRow, error) {
+	if len(*s) == 0 {
+		return nil, io.EOF
+	}
+	row := (*s)[0]
+	*s = (*s)[1:]
+	return row, nil
+}
+
+// StreamBudgetData uploads the rows of it as budget data without reading them all into
+// memory. Each call carries the rows of at most 366 inputs, an input being the rows of
+// one variable, unit and section whether they are consecutive or not.
+// The first failing call stops the upload and its error is returned.
+func (s *ForecastService) StreamBudgetData(ctx context.Context, it ForecastRowIterator, opts *StreamUploadOptions) (*StreamUploadResult, error) {
+	appendData := opts != nil && opts.AppendData
+	return s.streamUpload(ctx, it, opts, func(ctx context.Context, rows []*ForecastRow) error {
+		rs := sliceRowIterator(rows)
+		dil, err := ReadDataProviderInputs(&rs)
+		if err != nil {
+			return err
+		}
+		_, err = s.UploadBudgetData(ctx, appendData, dil)
+		return err
+	})
+}
+
+// StreamPredictedData uploads the rows of it as predicted data, see StreamBudgetData.
+// The configuration and run fields of every prediction are copied from template.
+func (s *ForecastService) StreamPredictedData(ctx context.Context, it ForecastRowIterator, template *ForecastPrediction, opts *StreamUploadOptions) (*StreamUploadResult, error) {
+	return s.streamUpload(ctx, it, opts, func(ctx context.Context, rows []*ForecastRow) error {
+		rs := sliceRowIterator(rows)
+		inlist, err := ReadForecastPredictions(&rs, template)
+		if err != nil {
+			return err
+		}
+		_, err = s.UploadPredictedData(ctx, inlist)
+		return err
+	})
+}
+
+func (s *ForecastService) streamUpload(ctx context.Context, it ForecastRowIterator, opts *StreamUploadOptions, send func(context.Context, []*ForecastRow) error) (*StreamUploadResult, error) {
+	inFlight := defaultStreamInFlight
+	if opts != nil && opts.InFlight > 0 {
+		inFlight = opts.InFlight
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	res := &StreamUploadResult{}
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	fail := func(err error) {
+		mu.Lock()
+		if firstErr == nil {
+			firstErr = err
+			cancel()
+		}
+		mu.Unlock()
+	}
+	slots := make(chan struct{}, inFlight)
+	flush := func(batch []*ForecastRow) bool {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return false
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := send(ctx, batch); err != nil {
+				fail(err)
+				return
+			}
+			mu.Lock()
+			res.Rows += len(batch)
+			res.Calls++
+			mu.Unlock()
+		}()
+		return true
+	}
+
+	next := it.Next
+	if cit, ok := it.(contextRowIterator); ok {
+		next = func() (*ForecastRow, error) { return cit.nextContext(ctx) }
+	}
+	var batch []*ForecastRow
+	inputs := map[forecastGroupKey]bool{}
+	for ctx.Err() == nil {
+		row, err := next()
+		if err == io.EOF {
+			if len(batch) > 0 {
+				flush(batch)
+			}
+			break
+		}
+		if err != nil {
+			if ctx.Err() == nil {
+				fail(err)
+			}
+			break
+		}
+		k := row.groupKey()
+		if (!inputs[k] && len(inputs) == maxRowsPerCall) || len(batch) == maxStreamPayloads {
+			if !flush(batch) {
+				break
+			}
+			batch = nil
+			inputs = map[forecastGroupKey]bool{}
+		}
+		inputs[k] = true
+		batch = append(batch, row)
+	}
+	wg.Wait()
+
+	if firstErr == nil && ctx.Err() != nil {
+		// The parent context was canceled, our own cancel is only deferred
+		firstErr = ctx.Err()
+	}
+	return res, firstErr
+}
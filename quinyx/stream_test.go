@@ -0,0 +1,110 @@
+package quinyx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+// testRowChan sends n rows of units round robin, so the rows of a unit are not consecutive
+func testRowChan(n, units int) <-chan *ForecastRow {
+	ch := make(chan *ForecastRow)
+	start := time.Date(2020, time.October, 12, 0, 0, 0, 0, time.UTC)
+	go func() {
+		defer close(ch)
+		for i := 0; i < n; i++ {
+			ch <- &ForecastRow{
+				ExternalForecastVariableID: "v",
+				ExternalUnitID:             strconv.Itoa(i % units),
+				Payload:                    &Payload{Data: Float64(float64(i)), Timestamp: &Timestamp{start.Add(time.Duration(i) * 15 * time.Minute)}},
+			}
+		}
+	}()
+	return ch
+}
+
+func TestStreamBudgetData(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var inFlight, maxInFlight, payloads int32
+	mux.HandleFunc("/forecasts/budget-data", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		assert.Equal(t, "true", r.URL.Query().Get("appendData"))
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		if n > atomic.LoadInt32(&maxInFlight) {
+			atomic.StoreInt32(&maxInFlight, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+		var dil DataProviderInputList
+		assert.NilError(t, json.NewDecoder(r.Body).Decode(&dil))
+		assert.Assert(t, len(dil.DataProviderInputs) <= maxRowsPerCall)
+		rows := 0
+		for _, in := range dil.DataProviderInputs {
+			rows += len(in.DataPayload)
+		}
+		atomic.AddInt32(&payloads, int32(rows))
+	})
+
+	// 800 units need three calls, the last one also takes the rows of units seen before
+	res, err := client.Forecast.StreamBudgetData(context.Background(), ChanRowIterator(testRowChan(1000, 800)), &StreamUploadOptions{InFlight: 2, AppendData: true})
+	assert.NilError(t, err)
+	assert.Equal(t, 1000, res.Rows)
+	assert.Equal(t, 3, res.Calls)
+	assert.Equal(t, int32(1000), atomic.LoadInt32(&payloads))
+	assert.Assert(t, atomic.LoadInt32(&maxInFlight) <= 2)
+
+	// A single long series is split to bound the memory of a batch
+	res, err = client.Forecast.StreamBudgetData(context.Background(), ChanRowIterator(testRowChan(maxStreamPayloads+1, 1)), &StreamUploadOptions{AppendData: true})
+	assert.NilError(t, err)
+	assert.Equal(t, 2, res.Calls)
+}
+
+func TestStreamCanceled(t *testing.T) {
+	client, _, _, teardown := setup()
+	defer teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		// Nothing is ever sent on or closes the channel
+		_, err := client.Forecast.StreamBudgetData(ctx, ChanRowIterator(make(chan *ForecastRow)), nil)
+		done <- err
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("StreamBudgetData did not return after cancel")
+	}
+}
+
+func TestStreamPredictedDataStopsOnError(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var calls int32
+	mux.HandleFunc("/forecasts/predicted-data", func(w http.ResponseWriter, r *http.Request) {
+		var inlist PredictedDataInputList
+		assert.NilError(t, json.NewDecoder(r.Body).Decode(&inlist))
+		assert.Equal(t, "run-1", *inlist.ForecastPredictions[0].RunIdentifier)
+		if atomic.AddInt32(&calls, 1) > 1 {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	})
+
+	template := &ForecastPrediction{RunIdentifier: String("run-1")}
+	res, err := client.Forecast.StreamPredictedData(context.Background(), ChanRowIterator(testRowChan(5000, 5000)), template, &StreamUploadOptions{InFlight: 1})
+	assert.ErrorContains(t, err, "400")
+	assert.Equal(t, 1, res.Calls)
+	assert.Equal(t, maxRowsPerCall, res.Rows)
+	assert.Assert(t, atomic.LoadInt32(&calls) < 14)
+}
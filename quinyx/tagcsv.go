@@ -0,0 +1,300 @@
+package quinyx
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tag CSV column names, custom fields get a column each named by
+// TagCSVCustomFieldPrefix and their label
+const (
+	TagCSVExternalID       = "externalId"
+	TagCSVName             = "name"
+	TagCSVCode             = "code"
+	TagCSVUnitExternalID   = "unitExternalId"
+	TagCSVInformation      = "information"
+	TagCSVStartDate        = "startDate"
+	TagCSVEndDate          = "endDate"
+	TagCSVUniqueScheduling = "uniqueScheduling"
+	// TagCSVCoordinates holds the geofences as latitude,longitude,radius separated by semicolons
+	TagCSVCoordinates       = "coordinates"
+	TagCSVCustomFieldPrefix = "custom:"
+)
+
+var tagCSVColumns = []string{
+	TagCSVExternalID, TagCSVName, TagCSVCode, TagCSVUnitExternalID, TagCSVInformation,
+	TagCSVStartDate, TagCSVEndDate, TagCSVUniqueScheduling, TagCSVCoordinates,
+}
+
+// tagCSVDate is the format of dates at midnight UTC, other times are written as RFC 3339
+const tagCSVDate = "2006-01-02"
+
+// TagCSVErrors holds every row error of a tag CSV file
+type TagCSVErrors []*ParseError
+
+func (e TagCSVErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// WriteTagsCSV writes tags to w with a header row, one row per tag and a column per custom field label
+func WriteTagsCSV(w io.Writer, tags []*Tag) error {
+	labels := map[string]bool{}
+	for _, t := range tags {
+		if t == nil {
+			continue
+		}
+		for _, cf := range t.CustomFields {
+			if cf != nil && cf.Label != nil {
+				labels[*cf.Label] = true
+			}
+		}
+	}
+	header := append([]string{}, tagCSVColumns...)
+	custom := sortedKeys(labels)
+	for _, l := range custom {
+		header = append(header, TagCSVCustomFieldPrefix+l)
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, t := range tags {
+		if t == nil {
+			continue
+		}
+		var coords []string
+		for _, c := range t.Coordinates {
+			if c.valid() {
+				coords = append(coords, fmt.Sprintf("%s,%s,%d", formatFloat(c.Latitude), formatFloat(c.Longitude), *c.Radius))
+			}
+		}
+		unique := ""
+		if t.UniqueScheduling != nil {
+			unique = strconv.FormatBool(*t.UniqueScheduling)
+		}
+		record := []string{
+			stringValue(t.ExternalID), stringValue(t.Name), stringValue(t.Code), stringValue(t.UnitExternalID),
+			stringValue(t.Information), formatTagCSVDate(t.StartDate), formatTagCSVDate(t.EndDate), unique,
+			strings.Join(coords, ";"),
+		}
+		values := map[string]string{}
+		for _, cf := range t.CustomFields {
+			if cf != nil && cf.Label != nil {
+				values[*cf.Label] = stringValue(cf.Value)
+			}
+		}
+		for _, l := range custom {
+			record = append(record, values[l])
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatTagCSVDate(ts *Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+	t := ts.UTC()
+	if t.Equal(t.Truncate(24 * time.Hour)) {
+		return t.Format(tagCSVDate)
+	}
+	return t.Format(time.RFC3339)
+}
+
+// ReadTagsCSV reads and validates the tags of a CSV file written by WriteTagsCSV. Every row
+// is checked before returning, and all problems are returned together as TagCSVErrors.
+// externalId and unitExternalId are required, dates are YYYY-MM-DD or RFC 3339 and empty
+// cells leave the field unset. Columns other than the known ones and custom fields are ignored.
+func ReadTagsCSV(r io.Reader, file string) ([]*Tag, error) {
+	cr, lines := newCSVReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, &ParseError{File: file, Line: 1, Err: err}
+	}
+	index := map[string]int{}
+	for i, h := range header {
+		index[strings.TrimSpace(h)] = i
+	}
+	for _, col := range []string{TagCSVExternalID, TagCSVUnitExternalID} {
+		if _, ok := index[col]; !ok {
+			return nil, &ParseError{File: file, Line: 1, Column: col, Err: fmt.Errorf("is missing")}
+		}
+	}
+	var custom []string
+	for h := range index {
+		if strings.HasPrefix(h, TagCSVCustomFieldPrefix) {
+			custom = append(custom, h)
+		}
+	}
+	sort.Strings(custom)
+
+	var tags []*Tag
+	var errs TagCSVErrors
+	seen := map[string]int{}
+	line := lines.recordLine(header)
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			if pe, ok := err.(*csv.ParseError); ok {
+				errs = append(errs, &ParseError{File: file, Line: pe.Line, Err: pe.Err})
+				continue
+			}
+			errs = append(errs, &ParseError{File: file, Line: line + 1, Err: err})
+			break
+		}
+		line = lines.recordLine(record)
+		fail := func(col string, err error) {
+			errs = append(errs, &ParseError{File: file, Line: line, Column: col, Err: err})
+		}
+		cell := func(col string) string {
+			if i, ok := index[col]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		opt := func(col string) *string {
+			if v := cell(col); v != "" {
+				return String(v)
+			}
+			return nil
+		}
+
+		t := &Tag{
+			ExternalID:     opt(TagCSVExternalID),
+			Name:           opt(TagCSVName),
+			Code:           opt(TagCSVCode),
+			UnitExternalID: opt(TagCSVUnitExternalID),
+			Information:    opt(TagCSVInformation),
+		}
+		if t.ExternalID == nil {
+			fail(TagCSVExternalID, fmt.Errorf("is required"))
+		} else if first, ok := seen[*t.ExternalID]; ok {
+			fail(TagCSVExternalID, fmt.Errorf("%s is also on line %d", *t.ExternalID, first))
+		} else {
+			seen[*t.ExternalID] = line
+		}
+		if t.UnitExternalID == nil {
+			fail(TagCSVUnitExternalID, fmt.Errorf("is required"))
+		}
+		for _, d := range []struct {
+			col string
+			dst **Timestamp
+		}{{TagCSVStartDate, &t.StartDate}, {TagCSVEndDate, &t.EndDate}} {
+			if v := cell(d.col); v != "" {
+				ts, err := parseTagCSVDate(v)
+				if err != nil {
+					fail(d.col, err)
+					continue
+				}
+				*d.dst = ts
+			}
+		}
+		if t.StartDate != nil && t.EndDate != nil && t.EndDate.Before(t.StartDate.Time) {
+			fail(TagCSVEndDate, fmt.Errorf("is before startDate"))
+		}
+		if v := cell(TagCSVUniqueScheduling); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				fail(TagCSVUniqueScheduling, fmt.Errorf("%q is not true or false", v))
+			} else {
+				t.UniqueScheduling = Bool(b)
+			}
+		}
+		if v := cell(TagCSVCoordinates); v != "" {
+			coords, err := parseTagCSVCoordinates(v)
+			if err == nil {
+				err = ValidateCoordinates(coords)
+			}
+			if err != nil {
+				fail(TagCSVCoordinates, err)
+			} else {
+				t.Coordinates = coords
+			}
+		}
+		for _, h := range custom {
+			if v := cell(h); v != "" {
+				t.CustomFields = append(t.CustomFields, &CustomField{Label: String(strings.TrimPrefix(h, TagCSVCustomFieldPrefix)), Value: String(v)})
+			}
+		}
+		tags = append(tags, t)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return tags, nil
+}
+
+func parseTagCSVDate(v string) (*Timestamp, error) {
+	if t, err := time.Parse(tagCSVDate, v); err == nil {
+		return &Timestamp{t}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a YYYY-MM-DD or RFC 3339 date", v)
+	}
+	return &Timestamp{t}, nil
+}
+
+func parseTagCSVCoordinates(v string) ([]*Coordinate, error) {
+	var coords []*Coordinate
+	for _, part := range strings.Split(v, ";") {
+		f := strings.Split(strings.TrimSpace(part), ",")
+		if len(f) != 3 {
+			return nil, fmt.Errorf("%q is not latitude,longitude,radius", part)
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(f[0]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("latitude %q: %v", f[0], err)
+		}
+		lon, err := strconv.ParseFloat(strings.TrimSpace(f[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("longitude %q: %v", f[1], err)
+		}
+		radius, err := strconv.ParseInt(strings.TrimSpace(f[2]), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("radius %q: %v", f[2], err)
+		}
+		coords = append(coords, &Coordinate{Latitude: Float64(lat), Longitude: Float64(lon), Radius: Int32(int32(radius))})
+	}
+	return coords, nil
+}
+
+// ExportTagsCSV writes all tags of the category to w, see WriteTagsCSV
+func (s *TagsService) ExportTagsCSV(ctx context.Context, categoryExternalID string, w io.Writer) error {
+	tags, _, err := s.GetAllTags(ctx, categoryExternalID)
+	if err != nil {
+		return err
+	}
+	return WriteTagsCSV(w, tags)
+}
+
+// ImportTagsCSV validates the whole file in r, see ReadTagsCSV, and only then creates the
+// tags that are not in the category and updates the ones that differ
+func (s *TagsService) ImportTagsCSV(ctx context.Context, categoryExternalID string, r io.Reader, file string) (*TagImportResult, error) {
+	tags, err := ReadTagsCSV(r, file)
+	if err != nil {
+		return nil, err
+	}
+	return s.importTags(ctx, categoryExternalID, tags)
+}
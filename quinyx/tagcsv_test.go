@@ -0,0 +1,109 @@
+package quinyx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestTagsCSVRoundTrip(t *testing.T) {
+	tags := []*Tag{
+		{
+			ExternalID:       String("p1"),
+			Name:             String("Project, one"),
+			UnitExternalID:   String("u1"),
+			StartDate:        &Timestamp{time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+			EndDate:          &Timestamp{time.Date(2021, 6, 30, 12, 0, 0, 0, time.UTC)},
+			UniqueScheduling: Bool(true),
+			Coordinates: []*Coordinate{
+				{Latitude: Float64(59.3), Longitude: Float64(18.06), Radius: Int32(100)},
+				{Latitude: Float64(57.7), Longitude: Float64(11.97), Radius: Int32(50)},
+			},
+			CustomFields: []*CustomField{{Label: String("cost center"), Value: String("42")}},
+		},
+		nil,
+		{ExternalID: String("p2"), UnitExternalID: String("u2"), CustomFields: []*CustomField{{Label: String("owner"), Value: String("finance")}}},
+	}
+	var buf bytes.Buffer
+	assert.NilError(t, WriteTagsCSV(&buf, tags))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, "externalId,name,code,unitExternalId,information,startDate,endDate,uniqueScheduling,coordinates,custom:cost center,custom:owner", lines[0])
+	assert.Equal(t, `p1,"Project, one",,u1,,2021-01-01,2021-06-30T12:00:00Z,true,"59.3,18.06,100;57.7,11.97,50",42,`, lines[1])
+	assert.Equal(t, "p2,,,u2,,,,,,,finance", lines[2])
+
+	got, err := ReadTagsCSV(&buf, "tags.csv")
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(got))
+	assert.Equal(t, 0, len(DiffTags(tags[0], got[0])))
+	assert.Equal(t, 0, len(DiffTags(tags[2], got[1])))
+	assert.Assert(t, got[1].UniqueScheduling == nil)
+}
+
+func TestReadTagsCSVErrors(t *testing.T) {
+	in := `externalId,unitExternalId,startDate,endDate,uniqueScheduling,coordinates
+a,u1,2021-13-01,,yes,
+,,,,,
+a,u1,2021-02-01,2021-01-01,,"91,0,10"
+`
+	_, err := ReadTagsCSV(strings.NewReader(in), "tags.csv")
+	errs, ok := err.(TagCSVErrors)
+	assert.Assert(t, ok, "%T", err)
+	var got []string
+	for _, e := range errs {
+		got = append(got, fmt.Sprintf("%d %s", e.Line, e.Column))
+	}
+	assert.DeepEqual(t, []string{
+		"2 startDate", "2 uniqueScheduling",
+		"3 externalId", "3 unitExternalId",
+		"4 externalId", "4 endDate", "4 coordinates",
+	}, got)
+
+	_, err = ReadTagsCSV(strings.NewReader("externalId,name\na,A\n"), "tags.csv")
+	assert.Error(t, err, `tags.csv:1: column "unitExternalId": is missing`)
+
+	// The quoted information spans two lines, so the second tag starts on line 4
+	_, err = ReadTagsCSV(strings.NewReader("externalId,unitExternalId,information\na,u1,\"first\nsecond\"\nb,,\n"), "tags.csv")
+	assert.Error(t, err, `tags.csv:4: column "unitExternalId": is required`)
+}
+
+func TestImportTagsCSV(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var calls []string
+	mux.HandleFunc("/tags/categories/projects/tags", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, `[{"categoryExternalId":"projects","externalId":"same","name":"Same","unitExternalId":"u1"},
+				{"categoryExternalId":"projects","externalId":"renamed","name":"Old","unitExternalId":"u1"}]`)
+			return
+		}
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/tags/categories/projects/tags/renamed", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PUT")
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		fmt.Fprint(w, `{}`)
+	})
+
+	// A single invalid row stops the whole import before any write
+	_, err := client.Tags.ImportTagsCSV(context.Background(), "projects", strings.NewReader("externalId,unitExternalId,uniqueScheduling\nnew,u1,\nbad,u1,maybe\n"), "p.csv")
+	assert.ErrorContains(t, err, "p.csv:3")
+	assert.Equal(t, 0, len(calls))
+
+	in := "externalId,name,unitExternalId\nsame,Same,u1\nrenamed,New,u1\nnew,New,u2\n"
+	res, err := client.Tags.ImportTagsCSV(context.Background(), "projects", strings.NewReader(in), "p.csv")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &TagImportResult{Created: []string{"new"}, Updated: []string{"renamed"}, Unchanged: []string{"same"}}, res)
+	assert.DeepEqual(t, []string{"PUT /tags/categories/projects/tags/renamed", "POST /tags/categories/projects/tags"}, calls)
+
+	var buf bytes.Buffer
+	assert.NilError(t, client.Tags.ExportTagsCSV(context.Background(), "projects", &buf))
+	assert.Assert(t, strings.Contains(buf.String(), "renamed,Old,,u1"))
+}
@@ -0,0 +1,273 @@
+package quinyx
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TagQuery selects tags in a TagIndex, empty fields match every tag
+type TagQuery struct {
+	CategoryExternalID string
+	ExternalID         string
+	Code               string
+	UnitExternalID     string
+	// NamePrefix matches names case-insensitively
+	NamePrefix string
+	// CustomFieldLabel and CustomFieldValue match tags with that custom field value
+	CustomFieldLabel string
+	CustomFieldValue string
+	// ActiveAt, if set, matches tags active at that time, see Tag.ActiveAt
+	ActiveAt time.Time
+}
+
+// IndexedTag is a tag in a TagIndex with its category
+type IndexedTag struct {
+	Category *TagCategory
+	Tag      *Tag
+}
+
+// TagIndexStats counts what a refresh changed
+type TagIndexStats struct {
+	Added   int
+	Updated int
+	Removed int
+}
+
+// TagIndex keeps all categories and tags in memory for fast lookups. Searches can run
+// concurrently with a refresh, and see the new tags once all categories are loaded.
+// The returned tags are shared and must not be modified.
+type TagIndex struct {
+	Tags *TagsService
+	// Interval between refreshes done by Run
+	Interval time.Duration
+	// OnError, if set, receives the errors of Run instead of stopping it
+	OnError func(error)
+
+	mu         sync.RWMutex
+	categories map[string]*TagCategory
+	byCategory map[string][]*Tag
+	byID       map[string][]*IndexedTag
+	byCode     map[string][]*IndexedTag
+	byUnit     map[string][]*IndexedTag
+	byName     []*IndexedTag // sorted by lower case name
+	all        []*IndexedTag
+}
+
+// NewTagIndex returns an empty index loading from tags, call Refresh to fill it
+func NewTagIndex(tags *TagsService) *TagIndex {
+	return &TagIndex{Tags: tags}
+}
+
+// Refresh reloads the categories and their tags. A category that fails to load keeps
+// its previous tags, and the first error is returned after all categories were tried.
+func (ix *TagIndex) Refresh(ctx context.Context) (*TagIndexStats, error) {
+	categories, _, err := ix.Tags.GetAllCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stats := &TagIndexStats{}
+	keep := map[string]bool{}
+	var firstErr error
+	for _, c := range categories {
+		if c == nil || c.ExternalID == nil {
+			continue
+		}
+		keep[*c.ExternalID] = true
+		if err := ix.refreshCategory(ctx, c, stats); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("category %s: %v", *c.ExternalID, err)
+		}
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	for id := range ix.categories {
+		if !keep[id] {
+			stats.Removed += len(ix.byCategory[id])
+			delete(ix.categories, id)
+			delete(ix.byCategory, id)
+		}
+	}
+	ix.reindex()
+	return stats, firstErr
+}
+
+// RefreshCategory reloads the tags of a single category
+func (ix *TagIndex) RefreshCategory(ctx context.Context, categoryExternalID string) (*TagIndexStats, error) {
+	c, _, err := ix.Tags.GetCategory(ctx, categoryExternalID)
+	if err != nil {
+		return nil, err
+	}
+	if c.ExternalID == nil {
+		c.ExternalID = String(categoryExternalID)
+	}
+	stats := &TagIndexStats{}
+	if err := ix.refreshCategory(ctx, c, stats); err != nil {
+		return stats, err
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.reindex()
+	return stats, nil
+}
+
+// refreshCategory loads the tags of the category and swaps them in, the caller
+// reindexes once all categories are loaded
+func (ix *TagIndex) refreshCategory(ctx context.Context, c *TagCategory, stats *TagIndexStats) error {
+	tags, _, err := ix.Tags.GetAllTags(ctx, *c.ExternalID)
+	if err != nil {
+		return err
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	old := map[string]*Tag{}
+	for _, t := range ix.byCategory[*c.ExternalID] {
+		if t != nil {
+			old[stringValue(t.ExternalID)] = t
+		}
+	}
+	for _, t := range tags {
+		if t == nil {
+			continue
+		}
+		prev, ok := old[stringValue(t.ExternalID)]
+		switch {
+		case !ok:
+			stats.Added++
+		case len(DiffTags(prev, t)) > 0:
+			stats.Updated++
+		}
+		delete(old, stringValue(t.ExternalID))
+	}
+	stats.Removed += len(old)
+	if ix.categories == nil {
+		ix.categories = map[string]*TagCategory{}
+		ix.byCategory = map[string][]*Tag{}
+	}
+	ix.categories[*c.ExternalID] = c
+	ix.byCategory[*c.ExternalID] = tags
+	return nil
+}
+
+// reindex rebuilds the lookup maps, ix.mu must be held for writing
+func (ix *TagIndex) reindex() {
+	ix.byID = map[string][]*IndexedTag{}
+	ix.byCode = map[string][]*IndexedTag{}
+	ix.byUnit = map[string][]*IndexedTag{}
+	ix.all = nil
+	ids := make([]string, 0, len(ix.byCategory))
+	for id := range ix.byCategory {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		for _, t := range ix.byCategory[id] {
+			if t == nil {
+				continue
+			}
+			it := &IndexedTag{Category: ix.categories[id], Tag: t}
+			ix.all = append(ix.all, it)
+			if t.ExternalID != nil {
+				ix.byID[*t.ExternalID] = append(ix.byID[*t.ExternalID], it)
+			}
+			if t.Code != nil {
+				ix.byCode[*t.Code] = append(ix.byCode[*t.Code], it)
+			}
+			if t.UnitExternalID != nil {
+				ix.byUnit[*t.UnitExternalID] = append(ix.byUnit[*t.UnitExternalID], it)
+			}
+		}
+	}
+	ix.byName = make([]*IndexedTag, len(ix.all))
+	copy(ix.byName, ix.all)
+	sort.SliceStable(ix.byName, func(i, j int) bool {
+		return strings.ToLower(stringValue(ix.byName[i].Tag.Name)) < strings.ToLower(stringValue(ix.byName[j].Tag.Name))
+	})
+}
+
+// Category returns the indexed category
+func (ix *TagIndex) Category(categoryExternalID string) *TagCategory {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.categories[categoryExternalID]
+}
+
+// Len returns the number of indexed tags
+func (ix *TagIndex) Len() int {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return len(ix.all)
+}
+
+// Search returns the tags matching every set field of q, ordered by category and
+// then as Quinyx listed them, or by name when NamePrefix is set.
+func (ix *TagIndex) Search(q TagQuery) []*IndexedTag {
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	var candidates []*IndexedTag
+	switch {
+	case q.ExternalID != "":
+		candidates = ix.byID[q.ExternalID]
+	case q.Code != "":
+		candidates = ix.byCode[q.Code]
+	case q.UnitExternalID != "":
+		candidates = ix.byUnit[q.UnitExternalID]
+	case q.NamePrefix != "":
+		prefix := strings.ToLower(q.NamePrefix)
+		i := sort.Search(len(ix.byName), func(i int) bool {
+			return strings.ToLower(stringValue(ix.byName[i].Tag.Name)) >= prefix
+		})
+		j := i
+		for j < len(ix.byName) && strings.HasPrefix(strings.ToLower(stringValue(ix.byName[j].Tag.Name)), prefix) {
+			j++
+		}
+		candidates = ix.byName[i:j]
+	default:
+		candidates = ix.all
+	}
+	var found []*IndexedTag
+	for _, it := range candidates {
+		if q.matches(it) {
+			found = append(found, it)
+		}
+	}
+	return found
+}
+
+func (q *TagQuery) matches(it *IndexedTag) bool {
+	t := it.Tag
+	switch {
+	case q.CategoryExternalID != "" && (it.Category == nil || stringValue(it.Category.ExternalID) != q.CategoryExternalID):
+		return false
+	case q.ExternalID != "" && stringValue(t.ExternalID) != q.ExternalID:
+		return false
+	case q.Code != "" && stringValue(t.Code) != q.Code:
+		return false
+	case q.UnitExternalID != "" && stringValue(t.UnitExternalID) != q.UnitExternalID:
+		return false
+	case q.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(stringValue(t.Name)), strings.ToLower(q.NamePrefix)):
+		return false
+	case !q.ActiveAt.IsZero() && !t.ActiveAt(q.ActiveAt):
+		return false
+	}
+	if q.CustomFieldLabel != "" {
+		for _, cf := range t.CustomFields {
+			if cf != nil && stringValue(cf.Label) == q.CustomFieldLabel && stringValue(cf.Value) == q.CustomFieldValue {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// Run refreshes the index right away and then every Interval until ctx is done
+func (ix *TagIndex) Run(ctx context.Context) error {
+	return runEvery(ctx, ix.Interval, func(ctx context.Context) error {
+		_, err := ix.Refresh(ctx)
+		return err
+	}, ix.OnError)
+}
@@ -0,0 +1,74 @@
+package quinyx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func indexedIDs(tags []*IndexedTag) []string {
+	var ids []string
+	for _, it := range tags {
+		ids = append(ids, *it.Tag.ExternalID)
+	}
+	return ids
+}
+
+func TestTagIndex(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var mu sync.Mutex
+	projects := `[{"externalId":"p1","name":"Alpha","code":"A","unitExternalId":"u1"},
+		{"externalId":"p2","name":"alpine","unitExternalId":"u2","endDate":"2020-01-01T00:00:00Z",
+		 "customFields":[{"label":"region","value":"north"}]}]`
+	mux.HandleFunc("/tags/categories", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"externalId":"cc","tagType":"COST_CENTER"},{"externalId":"pr","tagType":"PROJECT"}]`)
+	})
+	mux.HandleFunc("/tags/categories/cc/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[null,{"externalId":"c1","name":"Beta","code":"A","unitExternalId":"u1"}]`)
+	})
+	mux.HandleFunc("/tags/categories/pr/tags", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprint(w, projects)
+	})
+
+	ix := NewTagIndex(client.Tags)
+	stats, err := ix.Refresh(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, TagIndexStats{Added: 3}, *stats)
+	assert.Equal(t, 3, ix.Len())
+
+	assert.DeepEqual(t, []string{"c1", "p1"}, indexedIDs(ix.Search(TagQuery{Code: "A"})))
+	assert.DeepEqual(t, []string{"p1"}, indexedIDs(ix.Search(TagQuery{Code: "A", CategoryExternalID: "pr"})))
+	assert.DeepEqual(t, []string{"p1", "p2"}, indexedIDs(ix.Search(TagQuery{NamePrefix: "AL"})))
+	assert.DeepEqual(t, []string{"c1", "p1"}, indexedIDs(ix.Search(TagQuery{UnitExternalID: "u1"})))
+	assert.DeepEqual(t, []string{"p2"}, indexedIDs(ix.Search(TagQuery{CustomFieldLabel: "region", CustomFieldValue: "north"})))
+	assert.DeepEqual(t, []string{"c1", "p1"}, indexedIDs(ix.Search(TagQuery{ActiveAt: time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)})))
+	assert.Equal(t, "PROJECT", string(ix.Search(TagQuery{ExternalID: "p2"})[0].Category.TagType))
+
+	mu.Lock()
+	projects = `[{"externalId":"p1","name":"Alpha 2","code":"A","unitExternalId":"u1"},{"externalId":"p3","name":"Gamma"}]`
+	mu.Unlock()
+
+	// Searches keep working while refreshing
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			ix.Search(TagQuery{NamePrefix: "a"})
+		}
+	}()
+	stats, err = ix.Refresh(context.Background())
+	<-done
+	assert.NilError(t, err)
+	assert.Equal(t, TagIndexStats{Added: 1, Updated: 1, Removed: 1}, *stats)
+	assert.DeepEqual(t, []string{"p1"}, indexedIDs(ix.Search(TagQuery{NamePrefix: "alpha 2"})))
+	assert.Equal(t, 0, len(ix.Search(TagQuery{ExternalID: "p2"})))
+}
@@ -0,0 +1,132 @@
+package quinyx
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// TagMutation changes a field of a tag in place, see MutateTag
+type TagMutation func(t *Tag)
+
+// SetTagName sets the name of the tag
+func SetTagName(name string) TagMutation {
+	return func(t *Tag) { t.Name = String(name) }
+}
+
+// SetTagCode sets the code of the tag
+func SetTagCode(code string) TagMutation {
+	return func(t *Tag) { t.Code = String(code) }
+}
+
+// SetTagInformation sets the information of the tag
+func SetTagInformation(information string) TagMutation {
+	return func(t *Tag) { t.Information = String(information) }
+}
+
+// SetTagStartDate sets the start date of the tag
+func SetTagStartDate(date time.Time) TagMutation {
+	return func(t *Tag) { t.StartDate = &Timestamp{date} }
+}
+
+// SetTagEndDate sets the end date of the tag
+func SetTagEndDate(date time.Time) TagMutation {
+	return func(t *Tag) { t.EndDate = &Timestamp{date} }
+}
+
+// SetTagUniqueScheduling sets whether the tag is uniquely scheduled
+func SetTagUniqueScheduling(unique bool) TagMutation {
+	return func(t *Tag) { t.UniqueScheduling = Bool(unique) }
+}
+
+// SetTagCustomField sets the value of the custom field with label, adding it if missing.
+// The other custom fields are kept.
+func SetTagCustomField(label, value string) TagMutation {
+	return func(t *Tag) {
+		for _, cf := range t.CustomFields {
+			if cf != nil && stringValue(cf.Label) == label {
+				cf.Value = String(value)
+				return
+			}
+		}
+		t.CustomFields = append(t.CustomFields, &CustomField{Label: String(label), Value: String(value)})
+	}
+}
+
+// SetTagCoordinates replaces the geofences of the tag
+func SetTagCoordinates(coordinates ...*Coordinate) TagMutation {
+	return func(t *Tag) { t.Coordinates = coordinates }
+}
+
+// TagPatch returns the minimal delta for UpdateTag that turns current into desired,
+// together with the changed fields. Only fields set on desired count, so fields left
+// unset, such as CustomFields or Periods, are kept as they are. List fields are sent
+// whole when any element changed. The delta is nil when nothing changed.
+// A desired CategoryExternalID other than the current one returns a *CategoryChangeError,
+// and a nil current or desired returns ErrorReqfieldsMissing.
+func TagPatch(current, desired *Tag) (*Tag, []*TagFieldDiff, error) {
+	if current == nil || desired == nil || current.CategoryExternalID == nil || current.ExternalID == nil {
+		return nil, nil, ErrorReqfieldsMissing
+	}
+	if desired.CategoryExternalID != nil && *desired.CategoryExternalID != *current.CategoryExternalID {
+		return nil, nil, &CategoryChangeError{From: *current.CategoryExternalID, To: *desired.CategoryExternalID}
+	}
+	var fields []*TagFieldDiff
+	for _, d := range changedTagFields(current, desired) {
+		if d.Field != "ExternalID" {
+			fields = append(fields, d)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil, nil
+	}
+	delta := &Tag{CategoryExternalID: current.CategoryExternalID, ExternalID: current.ExternalID}
+	dv := reflect.ValueOf(delta).Elem()
+	for _, d := range fields {
+		dv.FieldByName(d.Field).Set(reflect.ValueOf(d.To))
+	}
+	return delta, fields, nil
+}
+
+// PatchTag sends the minimal delta between current and desired, see TagPatch.
+// It returns current without calling Quinyx when nothing changed.
+func (s *TagsService) PatchTag(ctx context.Context, current, desired *Tag) (*Tag, *Response, error) {
+	delta, _, err := TagPatch(current, desired)
+	if err != nil || delta == nil {
+		return current, nil, err
+	}
+	return s.UpdateTag(ctx, *current.CategoryExternalID, *current.ExternalID, delta)
+}
+
+// MutateTag fetches the tag, applies mutations to a copy of it and sends the minimal delta
+func (s *TagsService) MutateTag(ctx context.Context, categoryExternalID string, tagExternalID string, mutations ...TagMutation) (*Tag, *Response, error) {
+	current, resp, err := s.GetTag(ctx, categoryExternalID, tagExternalID)
+	if err != nil {
+		return nil, resp, err
+	}
+	if current.CategoryExternalID == nil {
+		current.CategoryExternalID = String(categoryExternalID)
+	}
+	if current.ExternalID == nil {
+		current.ExternalID = String(tagExternalID)
+	}
+	desired, err := copyTag(current)
+	if err != nil {
+		return nil, resp, err
+	}
+	for _, m := range mutations {
+		m(desired)
+	}
+	return s.PatchTag(ctx, current, desired)
+}
+
+// copyTag returns a deep copy of t
+func copyTag(t *Tag) (*Tag, error) {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	c := &Tag{}
+	return c, json.Unmarshal(b, c)
+}
@@ -0,0 +1,69 @@
+package quinyx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestTagPatch(t *testing.T) {
+	current := &Tag{
+		CategoryExternalID: String("cc"),
+		ExternalID:         String("t"),
+		Name:               String("Old"),
+		CustomFields:       []*CustomField{{Label: String("a"), Value: String("1")}},
+		Periods:            []*Period{{Type: PeriodTypePeriod, Hours: Float64(5)}},
+	}
+	delta, fields, err := TagPatch(current, &Tag{Name: String("New"), Code: String("c")})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &Tag{CategoryExternalID: String("cc"), ExternalID: String("t"), Code: String("c"), Name: String("New")}, delta)
+	assert.Equal(t, 2, len(fields))
+
+	delta, _, err = TagPatch(current, &Tag{Name: String("Old"), CustomFields: []*CustomField{{Label: String("a"), Value: String("1")}}})
+	assert.NilError(t, err)
+	assert.Assert(t, delta == nil)
+
+	_, _, err = TagPatch(current, &Tag{CategoryExternalID: String("other")})
+	var cerr *CategoryChangeError
+	assert.Assert(t, errors.As(err, &cerr))
+	assert.Equal(t, "other", cerr.To)
+	assert.ErrorContains(t, err, "categoryExternalID cannot be changed")
+
+	_, _, err = TagPatch(current, nil)
+	assert.Equal(t, ErrorReqfieldsMissing, err)
+	_, _, err = TagPatch(nil, current)
+	assert.Equal(t, ErrorReqfieldsMissing, err)
+}
+
+func TestMutateTag(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/tags/categories/cc/tags/t", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, `{"categoryExternalId":"cc","externalId":"t","name":"n",
+				"customFields":[{"label":"a","value":"1"},{"label":"b","value":"2"}],
+				"periods":[{"type":"PERIOD","hours":5}]}`)
+			return
+		}
+		testMethod(t, r, "PUT")
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NilError(t, err)
+		assert.Equal(t, `{"categoryExternalId":"cc","customFields":[{"label":"a","value":"1"},{"label":"b","value":"3"}],"endDate":"2020-12-31T00:00:00Z","externalId":"t"}
+`, string(body))
+		fmt.Fprint(w, string(body))
+	})
+
+	tag, _, err := client.Tags.MutateTag(context.Background(), "cc", "t",
+		SetTagCustomField("b", "3"),
+		SetTagName("n"),
+		SetTagEndDate(time.Date(2020, time.December, 31, 0, 0, 0, 0, time.UTC)))
+	assert.NilError(t, err)
+	assert.Equal(t, "3", *tag.CustomFields[1].Value)
+}
@@ -1,10 +1,16 @@
 package quinyx
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+
+	"github.com/google/go-querystring/query"
 )
 
 // TagsService handles Quinyx Tags
@@ -59,40 +65,45 @@ type TagCategory struct {
 	TagType    TagType `json:"tagType,omitempty"`
 }
 
-// TagType defines a type of Tag
+// TagType defines a type of Tag. Types Quinyx adds after this library are kept
+// as they are, see IsKnown.
 type TagType string
 
 // TagTypes
 const (
 	CostCenter TagType = "COST_CENTER"
-	Project            = "PROJECT"
-	Account            = "ACCOUNT"
-	Extended           = "EXTENDED"
+	Project    TagType = "PROJECT"
+	Account    TagType = "ACCOUNT"
+	Extended   TagType = "EXTENDED"
 )
 
-// PeriodType is the type of the Period
+// IsKnown reports whether the TagType is one of the TagTypes
+func (tt TagType) IsKnown() bool {
+	switch tt {
+	case CostCenter, Project, Account, Extended:
+		return true
+	}
+	return false
+}
+
+// PeriodType is the type of the Period. Types Quinyx adds after this library are
+// kept as they are, see IsKnown.
 type PeriodType string
 
 // PeriodType
 const (
 	PeriodTypePeriod PeriodType = "PERIOD"
-	PeriodTypeDays              = "DAYS"
-	PeriodTypeWeeks             = "WEEKS"
+	PeriodTypeDays   PeriodType = "DAYS"
+	PeriodTypeWeeks  PeriodType = "WEEKS"
 )
 
-// UnmarshalJSON TagType enum
-func (tt *TagType) UnmarshalJSON(b []byte) error {
-	type TT TagType
-	var r *TT = (*TT)(tt)
-	err := json.Unmarshal(b, &r)
-	if err != nil {
-		panic(err)
+// IsKnown reports whether the PeriodType is one of the PeriodTypes
+func (pt PeriodType) IsKnown() bool {
+	switch pt {
+	case PeriodTypePeriod, PeriodTypeDays, PeriodTypeWeeks:
+		return true
 	}
-	switch *tt {
-	case CostCenter, Project, Account, Extended:
-		return nil
-	}
-	return errors.New("Invalid leave type")
+	return false
 }
 
 // GetAllCategories gets all categories
@@ -125,20 +136,256 @@ func (s *TagsService) GetCategory(ctx context.Context, categoryExternalID string
 	return category, resp, nil
 }
 
-// GetAllTags based on categoryExternalID
-// While the documentation says it should return all, it actually only returns one tag.
-func (s *TagsService) GetAllTags(ctx context.Context, categoryExternalID string) (*Tag, *Response, error) {
+var (
+	// ErrorInvalidColor is the error returned when a TagCategory Color is not a hex color
+	ErrorInvalidColor = fmt.Errorf("Color must be a hex color such as #1A2B3C")
+	// ErrorInvalidTagType is the error returned when a TagCategory has an unknown TagType
+	ErrorInvalidTagType = fmt.Errorf("TagType must be one of COST_CENTER, PROJECT, ACCOUNT or EXTENDED")
+)
+
+var hexColor = regexp.MustCompile(`^#(?:[0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$`)
+
+// CategoryInUseError is returned when Quinyx refuses to change or delete a category that still has tags
+type CategoryInUseError struct {
+	CategoryExternalID string
+	Response           *ErrorResponse
+}
+
+func (e *CategoryInUseError) Error() string {
+	return fmt.Sprintf("category %s still has tags: %v", e.CategoryExternalID, e.Response)
+}
+
+func (e *CategoryInUseError) Unwrap() error {
+	return e.Response
+}
+
+// CategoryChangeError is returned when an update would move a tag to another category
+type CategoryChangeError struct {
+	From string
+	To   string
+}
+
+func (e *CategoryChangeError) Error() string {
+	return fmt.Sprintf("categoryExternalID cannot be changed from %s to %s", e.From, e.To)
+}
+
+// validate checks the fields of the category, requireAll is set when creating
+func (c *TagCategory) validate(requireAll bool) error {
+	if c == nil || requireAll && (c.ExternalID == nil || c.Name == nil || c.TagType == "") {
+		return ErrorReqfieldsMissing
+	}
+	if c.Color != nil && !hexColor.MatchString(*c.Color) {
+		return ErrorInvalidColor
+	}
+	if c.TagType != "" && !c.TagType.IsKnown() || requireAll && c.TagType == "" {
+		return ErrorInvalidTagType
+	}
+	return nil
+}
+
+// categoryInUse turns the 409 Conflict Quinyx answers for categories with tags into a *CategoryInUseError
+func categoryInUse(categoryExternalID string, err error) error {
+	if e, ok := err.(*ErrorResponse); ok && e.Response.StatusCode == http.StatusConflict {
+		return &CategoryInUseError{CategoryExternalID: categoryExternalID, Response: e}
+	}
+	return err
+}
+
+// CreateCategory creates and then returns the category. ExternalID, Name and TagType are required.
+func (s *TagsService) CreateCategory(ctx context.Context, category *TagCategory) (*TagCategory, *Response, error) {
+	if err := category.validate(true); err != nil {
+		return nil, nil, err
+	}
+	req, err := s.client.NewRequest("POST", "tags/categories", category)
+	if err != nil {
+		return nil, nil, err
+	}
+	var catres *TagCategory
+	resp, err := s.client.Do(ctx, req, &catres)
+	if err != nil {
+		return nil, resp, err
+	}
+	return catres, resp, nil
+}
+
+// UpdateCategory using a category delta object where set values will be changed.
+// Changing the TagType of a category with tags returns a *CategoryInUseError.
+func (s *TagsService) UpdateCategory(ctx context.Context, categoryExternalID string, category *TagCategory) (*TagCategory, *Response, error) {
+	if err := category.validate(false); err != nil {
+		return nil, nil, err
+	}
+	u := fmt.Sprintf("tags/categories/%v", categoryExternalID)
+	req, err := s.client.NewRequest("PUT", u, category)
+	if err != nil {
+		return nil, nil, err
+	}
+	var catres *TagCategory
+	resp, err := s.client.Do(ctx, req, &catres)
+	if err != nil {
+		return nil, resp, categoryInUse(categoryExternalID, err)
+	}
+	return catres, resp, nil
+}
+
+// DeleteCategory removes the category. Deleting a category with tags returns a *CategoryInUseError.
+func (s *TagsService) DeleteCategory(ctx context.Context, categoryExternalID string) (*Response, error) {
+	u := fmt.Sprintf("tags/categories/%v", categoryExternalID)
+	req, err := s.client.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(ctx, req, nil)
+	return resp, categoryInUse(categoryExternalID, err)
+}
+
+// GetAllTags returns every tag in the category, walking all pages
+func (s *TagsService) GetAllTags(ctx context.Context, categoryExternalID string) ([]*Tag, *Response, error) {
+	var tags []*Tag
+	it := s.TagIterator(ctx, categoryExternalID, nil)
+	for {
+		tag, err := it.Next()
+		if err == io.EOF {
+			return tags, it.Response(), nil
+		}
+		if err != nil {
+			return tags, it.Response(), err
+		}
+		tags = append(tags, tag)
+	}
+}
+
+// ListTags returns one page of the tags in the category. The next page, if any, is
+// in the NextPage or NextCursor of the Response.
+// Quinyx has been seen answering with a single tag instead of a list, which is
+// returned as a list of one.
+func (s *TagsService) ListTags(ctx context.Context, categoryExternalID string, opts *ListOptions) ([]*Tag, *Response, error) {
 	u := fmt.Sprintf("tags/categories/%v/tags", categoryExternalID)
 	req, err := s.client.NewRequest("GET", u, nil)
 	if err != nil {
 		return nil, nil, err
 	}
-	var tag *Tag
-	resp, err := s.client.Do(ctx, req, &tag)
+	if opts != nil {
+		v, err := query.Values(opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		req.URL.RawQuery = v.Encode()
+	}
+	var page tagPage
+	resp, err := s.client.Do(ctx, req, &page)
 	if err != nil {
 		return nil, resp, err
 	}
-	return tag, resp, nil
+	if !resp.HasNextPage() {
+		if page.NextCursor != "" {
+			resp.NextCursor = page.NextCursor
+		} else if page.paged && !page.Last && page.Number+1 < page.TotalPages {
+			resp.NextPage = page.Number + 1
+			resp.LastPage = page.TotalPages - 1
+		}
+	}
+	return page.Content, resp, nil
+}
+
+// tagPage decodes the tags list response, which can be a list, a single tag,
+// or a page object with the tags in content
+type tagPage struct {
+	Content    []*Tag `json:"content"`
+	Number     int    `json:"number"`
+	TotalPages int    `json:"totalPages"`
+	Last       bool   `json:"last"`
+	NextCursor string `json:"nextCursor"`
+	paged      bool
+}
+
+func (p *tagPage) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '[' {
+		return json.Unmarshal(b, &p.Content)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	if _, ok := fields["content"]; ok {
+		type page tagPage
+		p.paged = true
+		return json.Unmarshal(b, (*page)(p))
+	}
+	if fields == nil {
+		return nil
+	}
+	tag := &Tag{}
+	if err := json.Unmarshal(b, tag); err != nil {
+		return err
+	}
+	p.Content = []*Tag{tag}
+	return nil
+}
+
+// fieldsShape tells unknownFields how UnmarshalJSON decodes the response
+func (p *tagPage) fieldsShape(raw interface{}) (interface{}, reflect.Type) {
+	tags := reflect.TypeOf([]*Tag(nil))
+	if obj, ok := raw.(map[string]interface{}); ok {
+		if content, ok := obj["content"]; ok {
+			return content, tags
+		}
+		return raw, tags.Elem()
+	}
+	return raw, tags
+}
+
+// TagIterator walks the tags of a category page by page
+type TagIterator struct {
+	ctx                context.Context
+	s                  *TagsService
+	categoryExternalID string
+	opts               ListOptions
+	page               []*Tag
+	resp               *Response
+	done               bool
+}
+
+// TagIterator returns an iterator over all tags in the category, starting at the page in opts
+func (s *TagsService) TagIterator(ctx context.Context, categoryExternalID string, opts *ListOptions) *TagIterator {
+	it := &TagIterator{ctx: ctx, s: s, categoryExternalID: categoryExternalID}
+	if opts != nil {
+		it.opts = *opts
+	}
+	return it
+}
+
+// Next returns the next tag, fetching the next page when needed. It returns io.EOF after the last tag.
+func (it *TagIterator) Next() (*Tag, error) {
+	for len(it.page) == 0 {
+		if it.done {
+			return nil, io.EOF
+		}
+		tags, resp, err := it.s.ListTags(it.ctx, it.categoryExternalID, &it.opts)
+		it.resp = resp
+		if err != nil {
+			return nil, err
+		}
+		it.page = tags
+		switch {
+		case len(tags) == 0:
+			it.done = true
+		case resp.NextCursor != "":
+			it.opts.Cursor = resp.NextCursor
+		case resp.NextPage > 0:
+			it.opts.Page = resp.NextPage
+		default:
+			it.done = true
+		}
+	}
+	tag := it.page[0]
+	it.page = it.page[1:]
+	return tag, nil
+}
+
+// Response returns the response of the last page fetched
+func (it *TagIterator) Response() *Response {
+	return it.resp
 }
 
 // GetTag returns the specified tag by external tag category id and external tag id
@@ -156,9 +403,29 @@ func (s *TagsService) GetTag(ctx context.Context, categoryExternalID string, tag
 	return tag, resp, nil
 }
 
+// validatePeriods runs ValidatePeriods, and rejects unknown period types when
+// Client.StrictEnums is set
+func (s *TagsService) validatePeriods(periods []*Period) error {
+	if s.client.StrictEnums {
+		if err := checkEnums(reflect.ValueOf(periods)); err != nil {
+			return err
+		}
+	}
+	return ValidatePeriods(periods)
+}
+
 // CreateTag creates and then returns the tag
 func (s *TagsService) CreateTag(ctx context.Context, categoryExternalID string, tag *Tag) (*Tag, *Response, error) {
 	u := fmt.Sprintf("tags/categories/%v/tags", categoryExternalID)
+	if tag == nil {
+		return nil, nil, ErrorReqfieldsMissing
+	}
+	if err := ValidateCoordinates(tag.Coordinates); err != nil {
+		return nil, nil, err
+	}
+	if err := s.validatePeriods(tag.Periods); err != nil {
+		return nil, nil, err
+	}
 	req, err := s.client.NewRequest("POST", u, tag)
 	if err != nil {
 		return nil, nil, err
@@ -171,14 +438,30 @@ func (s *TagsService) CreateTag(ctx context.Context, categoryExternalID string,
 	return tagres, resp, nil
 }
 
-// UpdateTag using a tagdelta object where set values will be changed
+// UpdateTag using a tagdelta object where set values will be changed.
+// A delta without CategoryExternalID is sent with categoryExternalID, a different
+// one returns a *CategoryChangeError.
 func (s *TagsService) UpdateTag(ctx context.Context, categoryExternalID string, tagExternalID string, tag *Tag) (*Tag, *Response, error) {
 	u := fmt.Sprintf("tags/categories/%v/tags/%v", categoryExternalID, tagExternalID)
 
 	// See documentation for limitations on changing categoryExternalID
 	// https://api.quinyx.com/v2/docs/swagger-ui.html?urls.primaryName=tags#/tag-integration-api-controller/updateTagByExternalIdUsingPUT
+	if tag == nil {
+		return nil, nil, ErrorReqfieldsMissing
+	}
+	if tag.CategoryExternalID == nil {
+		delta := *tag
+		delta.CategoryExternalID = String(categoryExternalID)
+		tag = &delta
+	}
 	if *tag.CategoryExternalID != categoryExternalID {
-		return nil, nil, fmt.Errorf("categoryExternalID cannot be changed")
+		return nil, nil, &CategoryChangeError{From: categoryExternalID, To: *tag.CategoryExternalID}
+	}
+	if err := ValidateCoordinates(tag.Coordinates); err != nil {
+		return nil, nil, err
+	}
+	if err := s.validatePeriods(tag.Periods); err != nil {
+		return nil, nil, err
 	}
 
 	req, err := s.client.NewRequest("PUT", u, tag)
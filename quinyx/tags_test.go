@@ -3,7 +3,9 @@ package quinyx
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"testing"
@@ -114,7 +116,7 @@ func TestGetAllTags(t *testing.T) {
 		  }
 		  `)
 	})
-	tag, _, err := client.Tags.GetAllTags(context.Background(), "example")
+	tags, _, err := client.Tags.GetAllTags(context.Background(), "example")
 	assert.NilError(t, err)
 
 	want := &Tag{
@@ -148,7 +150,65 @@ func TestGetAllTags(t *testing.T) {
 		UniqueScheduling: Bool(true),
 		UnitExternalID:   String("uid"),
 	}
-	assert.DeepEqual(t, tag, want)
+	assert.DeepEqual(t, tags, []*Tag{want})
+}
+
+func TestGetAllTagsPaged(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/tags/categories/example/tags", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "GET")
+		switch r.URL.Query().Get("page") {
+		case "":
+			fmt.Fprint(w, `{"content":[{"externalId":"a"},{"externalId":"b"}],"number":0,"totalPages":2,"last":false}`)
+		case "1":
+			fmt.Fprint(w, `{"content":[{"externalId":"c"}],"number":1,"totalPages":2,"last":true}`)
+		default:
+			t.Errorf("unexpected page %q", r.URL.Query().Get("page"))
+		}
+	})
+
+	tags, resp, err := client.Tags.GetAllTags(context.Background(), "example")
+	assert.NilError(t, err)
+	assert.Equal(t, 3, len(tags))
+	assert.Equal(t, "c", *tags[2].ExternalID)
+	assert.Assert(t, !resp.HasNextPage())
+
+	tags, resp, err = client.Tags.ListTags(context.Background(), "example", &ListOptions{Size: 2})
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(tags))
+	assert.Equal(t, 1, resp.NextPage)
+	assert.Equal(t, 1, resp.LastPage)
+}
+
+func TestTagIteratorCursorLinks(t *testing.T) {
+	client, mux, serverURL, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/tags/categories/example/tags", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "50", r.URL.Query().Get("size"))
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Header().Set("Link", fmt.Sprintf(`<%s/tags/categories/example/tags?cursor=xyz&size=50>; rel="next"`, serverURL))
+			fmt.Fprint(w, `[{"externalId":"a"}]`)
+		case "xyz":
+			fmt.Fprint(w, `[{"externalId":"b"}]`)
+		}
+	})
+
+	it := client.Tags.TagIterator(context.Background(), "example", &ListOptions{Size: 50})
+	var ids []string
+	for {
+		tag, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NilError(t, err)
+		ids = append(ids, *tag.ExternalID)
+	}
+	assert.DeepEqual(t, []string{"a", "b"}, ids)
+	assert.Equal(t, "", it.Response().NextCursor)
 }
 
 func TestGetTag(t *testing.T) {
@@ -282,6 +342,9 @@ func TestCreateTag(t *testing.T) {
 	tag, _, err := client.Tags.CreateTag(context.Background(), "example", want)
 	assert.NilError(t, err)
 	assert.DeepEqual(t, tag, want)
+
+	_, _, err = client.Tags.CreateTag(context.Background(), "example", nil)
+	assert.Equal(t, ErrorReqfieldsMissing, err)
 }
 
 func TestUpdateTag(t *testing.T) {
@@ -352,6 +415,24 @@ func TestUpdateTag(t *testing.T) {
 	assert.ErrorContains(t, err, "categoryExternalID cannot be changed")
 }
 
+func TestUpdateTagWithoutCategory(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/tags/categories/cc/tags/t", func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NilError(t, err)
+		assert.Equal(t, `{"categoryExternalId":"cc","name":"n"}
+`, string(body))
+		fmt.Fprint(w, `{}`)
+	})
+
+	delta := &Tag{Name: String("n")}
+	_, _, err := client.Tags.UpdateTag(context.Background(), "cc", "t", delta)
+	assert.NilError(t, err)
+	assert.Assert(t, delta.CategoryExternalID == nil)
+}
+
 func TestDeleteTag(t *testing.T) {
 	client, mux, _, teardown := setup()
 	defer teardown()
@@ -362,3 +443,69 @@ func TestDeleteTag(t *testing.T) {
 	_, err := client.Tags.DeleteTag(context.Background(), "example", "eid")
 	assert.NilError(t, err)
 }
+
+func TestCreateCategory(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/tags/categories", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "POST")
+		body, err := ioutil.ReadAll(r.Body)
+		assert.NilError(t, err)
+		assert.Equal(t, `{"color":"#1a2B3c","externalId":"cc","name":"Cost centers","tagType":"COST_CENTER"}
+`, string(body))
+		fmt.Fprint(w, `{"color":"#1a2B3c","externalId":"cc","id":7,"name":"Cost centers","tagType":"COST_CENTER"}`)
+	})
+
+	category := &TagCategory{Color: String("#1a2B3c"), ExternalID: String("cc"), Name: String("Cost centers"), TagType: CostCenter}
+	created, _, err := client.Tags.CreateCategory(context.Background(), category)
+	assert.NilError(t, err)
+	assert.Equal(t, int32(7), *created.TagID)
+
+	_, _, err = client.Tags.CreateCategory(context.Background(), &TagCategory{ExternalID: String("cc"), Name: String("n")})
+	assert.Equal(t, ErrorReqfieldsMissing, err)
+	_, _, err = client.Tags.CreateCategory(context.Background(), &TagCategory{ExternalID: String("cc"), Name: String("n"), TagType: "OTHER"})
+	assert.Equal(t, ErrorInvalidTagType, err)
+	_, _, err = client.Tags.CreateCategory(context.Background(), &TagCategory{ExternalID: String("cc"), Name: String("n"), TagType: Project, Color: String("red")})
+	assert.Equal(t, ErrorInvalidColor, err)
+}
+
+func TestUpdateCategory(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/tags/categories/cc", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "PUT")
+		fmt.Fprint(w, `{"color":"#fff","externalId":"cc","name":"Renamed","tagType":"COST_CENTER"}`)
+	})
+
+	updated, _, err := client.Tags.UpdateCategory(context.Background(), "cc", &TagCategory{Name: String("Renamed"), Color: String("#fff")})
+	assert.NilError(t, err)
+	assert.Equal(t, "Renamed", *updated.Name)
+
+	_, _, err = client.Tags.UpdateCategory(context.Background(), "cc", &TagCategory{Color: String("#ffff")})
+	assert.Equal(t, ErrorInvalidColor, err)
+}
+
+func TestDeleteCategory(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/tags/categories/empty", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "DELETE")
+	})
+	mux.HandleFunc("/tags/categories/used", func(w http.ResponseWriter, r *http.Request) {
+		testMethod(t, r, "DELETE")
+		w.WriteHeader(http.StatusConflict)
+		fmt.Fprint(w, `{"message":"Category has tags"}`)
+	})
+
+	_, err := client.Tags.DeleteCategory(context.Background(), "empty")
+	assert.NilError(t, err)
+
+	_, err = client.Tags.DeleteCategory(context.Background(), "used")
+	var inUse *CategoryInUseError
+	assert.Assert(t, errors.As(err, &inUse))
+	assert.Equal(t, "used", inUse.CategoryExternalID)
+	assert.Equal(t, "Category has tags", inUse.Response.Message)
+}
@@ -0,0 +1,346 @@
+package quinyx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultTagSyncConcurrency = 4
+
+// TagFieldDiff is a field that differs between two versions of a tag
+type TagFieldDiff struct {
+	// Field is the name of the Tag field, e.g. "Name"
+	Field string
+	From  interface{}
+	To    interface{}
+}
+
+func (d *TagFieldDiff) String() string {
+	return fmt.Sprintf("%s: %s -> %s", d.Field, tagFieldString(d.From), tagFieldString(d.To))
+}
+
+// DiffTags returns the fields of the Tag that differ between from and to, in field
+// order. Timestamps are compared as instants and empty lists equal missing ones.
+// A nil tag compares as an empty one.
+func DiffTags(from, to *Tag) []*TagFieldDiff {
+	if from == nil {
+		from = &Tag{}
+	}
+	if to == nil {
+		to = &Tag{}
+	}
+	fv, tv := reflect.ValueOf(from).Elem(), reflect.ValueOf(to).Elem()
+	var diffs []*TagFieldDiff
+	for i := 0; i < fv.NumField(); i++ {
+		a, b := fv.Field(i), tv.Field(i)
+		if !equalTagField(a, b) {
+			diffs = append(diffs, &TagFieldDiff{Field: fv.Type().Field(i).Name, From: a.Interface(), To: b.Interface()})
+		}
+	}
+	return diffs
+}
+
+func isEmptyTagField(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr:
+		return v.IsNil()
+	case reflect.Slice:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func equalTagField(a, b reflect.Value) bool {
+	if isEmptyTagField(a) || isEmptyTagField(b) {
+		return isEmptyTagField(a) && isEmptyTagField(b)
+	}
+	if ta, ok := a.Interface().(*Timestamp); ok {
+		return ta.Equal(*b.Interface().(*Timestamp))
+	}
+	ja, _ := json.Marshal(a.Interface())
+	jb, _ := json.Marshal(b.Interface())
+	return bytes.Equal(ja, jb)
+}
+
+func tagFieldString(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() || isEmptyTagField(rv) {
+		return "<none>"
+	}
+	if rv.Kind() == reflect.Ptr && rv.Elem().Kind() != reflect.Struct {
+		return fmt.Sprint(rv.Elem().Interface())
+	}
+	if ts, ok := v.(*Timestamp); ok {
+		return ts.UTC().Format(time.RFC3339)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// TagSyncAction is what the TagSyncer does to a tag
+type TagSyncAction string
+
+// TagSyncActions
+const (
+	TagCreate  TagSyncAction = "create"
+	TagUpdate  TagSyncAction = "update"
+	TagDelete  TagSyncAction = "delete"
+	TagEndDate TagSyncAction = "end-date"
+)
+
+// TagChange is a change the TagSyncer made, or would make in a dry run
+type TagChange struct {
+	Action     TagSyncAction
+	ExternalID string
+	// Current is the tag in Quinyx, nil for creates
+	Current *Tag
+	// Sent is the tag sent to Quinyx, nil for deletes
+	Sent *Tag
+	// Fields are the changed fields of updates and end-dates
+	Fields []*TagFieldDiff
+	// Err is the error of the call, if it failed
+	Err error
+}
+
+// TagSyncReport summarizes a sync
+type TagSyncReport struct {
+	CategoryExternalID string
+	DryRun             bool
+	// Changes ordered by ExternalID
+	Changes   []*TagChange
+	Unchanged int
+}
+
+// Count returns the number of changes with action, failed ones included
+func (r *TagSyncReport) Count(action TagSyncAction) int {
+	n := 0
+	for _, c := range r.Changes {
+		if c.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed returns the changes that failed
+func (r *TagSyncReport) Failed() []*TagChange {
+	var failed []*TagChange
+	for _, c := range r.Changes {
+		if c.Err != nil {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+func (r *TagSyncReport) String() string {
+	var b strings.Builder
+	if r.DryRun {
+		b.WriteString("dry run: ")
+	}
+	fmt.Fprintf(&b, "%s: %d created, %d updated, %d deleted, %d end-dated, %d unchanged, %d failed",
+		r.CategoryExternalID, r.Count(TagCreate), r.Count(TagUpdate), r.Count(TagDelete), r.Count(TagEndDate), r.Unchanged, len(r.Failed()))
+	for _, c := range r.Changes {
+		fmt.Fprintf(&b, "\n%s %s", c.Action, c.ExternalID)
+		for _, f := range c.Fields {
+			fmt.Fprintf(&b, "\n  %s", f)
+		}
+		if c.Err != nil {
+			fmt.Fprintf(&b, "\n  error: %v", c.Err)
+		}
+	}
+	return b.String()
+}
+
+// TagSyncer mirrors tags from an external source of truth into a Quinyx category
+type TagSyncer struct {
+	Tags *TagsService
+	// DryRun only reports the changes
+	DryRun bool
+	// Concurrency is the maximum number of calls in flight, defaults to 4
+	Concurrency int
+	// EndDateMissing sets EndDate on tags missing from the desired set instead of deleting them.
+	// EndDate is inclusive, so the tags stay active until the end of the day of the sync.
+	EndDateMissing bool
+	// Now returns the end date of end-dated tags, defaults to time.Now
+	Now func() time.Time
+}
+
+// Sync makes the tags of the category match desired, keyed by ExternalID. Fields that are
+// not set on a desired tag are left as they are in Quinyx. Calls that fail are reported in
+// the Err of their change and the returned error counts them.
+func (s *TagSyncer) Sync(ctx context.Context, categoryExternalID string, desired []*Tag) (*TagSyncReport, error) {
+	want := map[string]*Tag{}
+	for i, tag := range desired {
+		if tag == nil || tag.ExternalID == nil {
+			return nil, fmt.Errorf("desired tag %d has no ExternalID", i)
+		}
+		if _, ok := want[*tag.ExternalID]; ok {
+			return nil, fmt.Errorf("desired tag %s is listed twice", *tag.ExternalID)
+		}
+		want[*tag.ExternalID] = tag
+	}
+	current, _, err := s.Tags.GetAllTags(ctx, categoryExternalID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &TagSyncReport{CategoryExternalID: categoryExternalID, DryRun: s.DryRun}
+	seen := map[string]bool{}
+	for _, cur := range current {
+		if cur == nil {
+			continue
+		}
+		id := stringValue(cur.ExternalID)
+		seen[id] = true
+		if tag, ok := want[id]; ok {
+			if change := s.planUpdate(categoryExternalID, cur, tag); change != nil {
+				report.Changes = append(report.Changes, change)
+			} else {
+				report.Unchanged++
+			}
+			continue
+		}
+		if change := s.planMissing(categoryExternalID, cur); change != nil {
+			report.Changes = append(report.Changes, change)
+		} else {
+			report.Unchanged++
+		}
+	}
+	for id, tag := range want {
+		if !seen[id] {
+			sent := *tag
+			sent.CategoryExternalID = String(categoryExternalID)
+			report.Changes = append(report.Changes, &TagChange{Action: TagCreate, ExternalID: id, Sent: &sent})
+		}
+	}
+	sort.Slice(report.Changes, func(i, j int) bool { return report.Changes[i].ExternalID < report.Changes[j].ExternalID })
+
+	if s.DryRun {
+		return report, nil
+	}
+	s.apply(ctx, categoryExternalID, report.Changes)
+	if failed := len(report.Failed()); failed > 0 {
+		return report, fmt.Errorf("%d of %d tag changes failed", failed, len(report.Changes))
+	}
+	return report, ctx.Err()
+}
+
+// changedTagFields returns the fields set on tag that differ from cur, leaving out the category
+func changedTagFields(cur, tag *Tag) []*TagFieldDiff {
+	var fields []*TagFieldDiff
+	for _, d := range DiffTags(cur, tag) {
+		if d.Field != "CategoryExternalID" && !isEmptyTagField(reflect.ValueOf(d.To)) {
+			fields = append(fields, d)
+		}
+	}
+	return fields
+}
+
+func (s *TagSyncer) planUpdate(categoryExternalID string, cur, tag *Tag) *TagChange {
+	fields := changedTagFields(cur, tag)
+	if len(fields) == 0 {
+		return nil
+	}
+	sent := *tag
+	sent.CategoryExternalID = String(categoryExternalID)
+	return &TagChange{Action: TagUpdate, ExternalID: *tag.ExternalID, Current: cur, Sent: &sent, Fields: fields}
+}
+
+func (s *TagSyncer) planMissing(categoryExternalID string, cur *Tag) *TagChange {
+	id := stringValue(cur.ExternalID)
+	if !s.EndDateMissing {
+		return &TagChange{Action: TagDelete, ExternalID: id, Current: cur}
+	}
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	end := now()
+	if cur.EndDate != nil && !cur.EndDate.After(end) {
+		return nil
+	}
+	sent := &Tag{CategoryExternalID: String(categoryExternalID), ExternalID: cur.ExternalID, EndDate: &Timestamp{end}}
+	return &TagChange{Action: TagEndDate, ExternalID: id, Current: cur, Sent: sent, Fields: []*TagFieldDiff{{Field: "EndDate", From: cur.EndDate, To: sent.EndDate}}}
+}
+
+func (s *TagSyncer) apply(ctx context.Context, categoryExternalID string, changes []*TagChange) {
+	concurrency := defaultTagSyncConcurrency
+	if s.Concurrency > 0 {
+		concurrency = s.Concurrency
+	}
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, c := range changes {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			c.Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(c *TagChange) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			switch c.Action {
+			case TagCreate:
+				_, _, c.Err = s.Tags.CreateTag(ctx, categoryExternalID, c.Sent)
+			case TagUpdate, TagEndDate:
+				_, _, c.Err = s.Tags.UpdateTag(ctx, categoryExternalID, c.ExternalID, c.Sent)
+			case TagDelete:
+				_, c.Err = s.Tags.DeleteTag(ctx, categoryExternalID, c.ExternalID)
+			}
+		}(c)
+	}
+	wg.Wait()
+}
+
+// TagImportResult lists the ExternalIDs of the tags an import touched
+type TagImportResult struct {
+	Created   []string
+	Updated   []string
+	Unchanged []string
+}
+
+// importTags creates the tags missing from the category and updates the ones that differ
+func (s *TagsService) importTags(ctx context.Context, categoryExternalID string, tags []*Tag) (*TagImportResult, error) {
+	current, _, err := s.GetAllTags(ctx, categoryExternalID)
+	if err != nil {
+		return nil, err
+	}
+	byID := map[string]*Tag{}
+	for _, t := range current {
+		if t == nil {
+			continue
+		}
+		byID[stringValue(t.ExternalID)] = t
+	}
+	res := &TagImportResult{}
+	for _, tag := range tags {
+		t := *tag
+		t.CategoryExternalID = String(categoryExternalID)
+		cur, ok := byID[*t.ExternalID]
+		switch {
+		case !ok:
+			if _, _, err := s.CreateTag(ctx, categoryExternalID, &t); err != nil {
+				return res, fmt.Errorf("create tag %s: %w", *t.ExternalID, err)
+			}
+			res.Created = append(res.Created, *t.ExternalID)
+		case len(changedTagFields(cur, &t)) > 0:
+			if _, _, err := s.UpdateTag(ctx, categoryExternalID, *t.ExternalID, &t); err != nil {
+				return res, fmt.Errorf("update tag %s: %w", *t.ExternalID, err)
+			}
+			res.Updated = append(res.Updated, *t.ExternalID)
+		default:
+			res.Unchanged = append(res.Unchanged, *t.ExternalID)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,101 @@
+package quinyx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestDiffTags(t *testing.T) {
+	ts := time.Date(2020, time.October, 12, 0, 0, 0, 0, time.UTC)
+	loc := time.FixedZone("CEST", 2*60*60)
+	from := &Tag{Name: String("a"), EndDate: &Timestamp{ts}, CustomFields: []*CustomField{}}
+	to := &Tag{Name: String("b"), EndDate: &Timestamp{ts.In(loc)}, Code: String("c")}
+	diffs := DiffTags(from, to)
+	assert.Equal(t, 2, len(diffs))
+	assert.Equal(t, "Code: <none> -> c", diffs[0].String())
+	assert.Equal(t, "Name: a -> b", diffs[1].String())
+	assert.Equal(t, 0, len(DiffTags(nil, &Tag{Periods: []*Period{}})))
+}
+
+func TestTagSyncer(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	var mu sync.Mutex
+	var calls []string
+	record := func(r *http.Request, body string) {
+		mu.Lock()
+		calls = append(calls, r.Method+" "+r.URL.Path+" "+body)
+		mu.Unlock()
+	}
+	mux.HandleFunc("/tags/categories/cc/tags", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			fmt.Fprint(w, `[null,
+				{"categoryExternalId":"cc","externalId":"same","name":"Same"},
+				{"categoryExternalId":"cc","externalId":"renamed","name":"Old","code":"keep"},
+				{"categoryExternalId":"cc","externalId":"gone","name":"Gone"},
+				{"categoryExternalId":"cc","externalId":"ended","endDate":"2020-01-01T00:00:00Z"}]`)
+			return
+		}
+		var tag Tag
+		assert.NilError(t, json.NewDecoder(r.Body).Decode(&tag))
+		record(r, *tag.Name)
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/tags/categories/cc/tags/", func(w http.ResponseWriter, r *http.Request) {
+		var tag Tag
+		if r.Method == "PUT" {
+			assert.NilError(t, json.NewDecoder(r.Body).Decode(&tag))
+			assert.Equal(t, "cc", *tag.CategoryExternalID)
+		}
+		body := ""
+		if tag.Name != nil {
+			body = *tag.Name
+		} else if tag.EndDate != nil {
+			body = tag.EndDate.UTC().Format(time.RFC3339)
+		}
+		record(r, body)
+		fmt.Fprint(w, `{}`)
+	})
+
+	desired := []*Tag{
+		{ExternalID: String("same"), Name: String("Same")},
+		{ExternalID: String("renamed"), Name: String("New")},
+		{ExternalID: String("new"), Name: String("Brand new")},
+	}
+	syncer := &TagSyncer{Tags: client.Tags, DryRun: true}
+	report, err := syncer.Sync(context.Background(), "cc", desired)
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(calls))
+	assert.Equal(t, `dry run: cc: 1 created, 1 updated, 2 deleted, 0 end-dated, 1 unchanged, 0 failed
+delete ended
+delete gone
+create new
+update renamed
+  Name: Old -> New`, report.String())
+
+	syncer.DryRun = false
+	syncer.EndDateMissing = true
+	syncer.Now = func() time.Time { return time.Date(2020, time.October, 12, 0, 0, 0, 0, time.UTC) }
+	report, err = syncer.Sync(context.Background(), "cc", desired)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, report.Count(TagEndDate))
+	assert.Equal(t, 2, report.Unchanged)
+	sort.Strings(calls)
+	assert.DeepEqual(t, []string{
+		"POST /tags/categories/cc/tags Brand new",
+		"PUT /tags/categories/cc/tags/gone 2020-10-12T00:00:00Z",
+		"PUT /tags/categories/cc/tags/renamed New",
+	}, calls)
+
+	_, err = syncer.Sync(context.Background(), "cc", []*Tag{{Name: String("no id")}})
+	assert.ErrorContains(t, err, "no ExternalID")
+}
@@ -0,0 +1,228 @@
+package quinyx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// TagEventType is the kind of change of a TagEvent
+type TagEventType string
+
+// TagEventTypes
+const (
+	TagAdded    TagEventType = "added"
+	TagModified TagEventType = "modified"
+	TagRemoved  TagEventType = "removed"
+)
+
+// TagEvent is a change of a tag seen by the TagWatcher
+type TagEvent struct {
+	Type               TagEventType
+	CategoryExternalID string
+	ExternalID         string
+	// Tag is the tag as it is now, nil when removed
+	Tag *Tag
+	// Previous is the tag as it was, nil when added
+	Previous *Tag
+	// Fields are the changed fields when modified
+	Fields []*TagFieldDiff
+	// Time is when the change was seen
+	Time time.Time
+}
+
+// TagState is the last known tags, by category and tag ExternalID
+type TagState map[string]map[string]*Tag
+
+// TagStateStore persists the TagState of a TagWatcher across restarts
+type TagStateStore interface {
+	// LoadTagState returns nil when no state was saved yet
+	LoadTagState() (TagState, error)
+	SaveTagState(state TagState) error
+}
+
+// FileTagStateStore is a TagStateStore keeping the state in a JSON file
+type FileTagStateStore struct {
+	Path string
+}
+
+// LoadTagState reads the state file
+func (s *FileTagStateStore) LoadTagState() (TagState, error) {
+	b, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state TagState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("%s: %v", s.Path, err)
+	}
+	return state, nil
+}
+
+// SaveTagState replaces the state file
+func (s *FileTagStateStore) SaveTagState(state TagState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(s.Path+".tmp", b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(s.Path+".tmp", s.Path)
+}
+
+// TagWatcher polls Quinyx for tag changes and emits them as events
+type TagWatcher struct {
+	Tags *TagsService
+	// Store, if set, keeps the last known state so a restart only emits what changed meanwhile
+	Store TagStateStore
+	// Categories to watch, all categories when empty
+	Categories []string
+	// Interval between polls done by Run
+	Interval time.Duration
+	// Events, if set, receives every event before the state is saved. Events of a
+	// poll that is canceled or fails to save are sent again by the next poll.
+	Events chan<- *TagEvent
+	// OnEvent, if set, is called for every event once the state is saved
+	OnEvent func(*TagEvent)
+	// OnError, if set, receives the errors of Run instead of stopping it
+	OnError func(error)
+
+	state  TagState
+	loaded bool
+}
+
+// Poll fetches the watched categories, emits the changes since the last poll and saves
+// the new state, which only moves on once it is saved. Without a saved state every tag
+// is emitted as added. A category that cannot be fetched keeps its last known state and
+// the first error is returned.
+func (w *TagWatcher) Poll(ctx context.Context) ([]*TagEvent, error) {
+	if !w.loaded {
+		if w.Store != nil {
+			state, err := w.Store.LoadTagState()
+			if err != nil {
+				return nil, err
+			}
+			w.state = state
+		}
+		if w.state == nil {
+			w.state = TagState{}
+		}
+		w.loaded = true
+	}
+
+	categories := w.Categories
+	all := len(categories) == 0
+	if all {
+		cats, _, err := w.Tags.GetAllCategories(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range cats {
+			if c != nil && c.ExternalID != nil {
+				categories = append(categories, *c.ExternalID)
+			}
+		}
+	}
+
+	now := time.Now()
+	next := TagState{}
+	var events []*TagEvent
+	var firstErr error
+	for _, cat := range categories {
+		tags, _, err := w.Tags.GetAllTags(ctx, cat)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("category %s: %v", cat, err)
+			}
+			if prev, ok := w.state[cat]; ok {
+				next[cat] = prev
+			}
+			continue
+		}
+		cur := map[string]*Tag{}
+		for _, t := range tags {
+			if t != nil && t.ExternalID != nil {
+				cur[*t.ExternalID] = t
+			}
+		}
+		next[cat] = cur
+		events = append(events, diffTagMaps(cat, w.state[cat], cur, now)...)
+	}
+	// Tags of categories that disappeared are removed, when watching all of them
+	if all {
+		for cat, prev := range w.state {
+			if _, ok := next[cat]; !ok {
+				events = append(events, diffTagMaps(cat, prev, nil, now)...)
+			}
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].CategoryExternalID != events[j].CategoryExternalID {
+			return events[i].CategoryExternalID < events[j].CategoryExternalID
+		}
+		return events[i].ExternalID < events[j].ExternalID
+	})
+
+	if w.Events != nil {
+		for _, e := range events {
+			select {
+			case w.Events <- e:
+			case <-ctx.Done():
+				// The state is not saved, so the events are sent again next time
+				return events, ctx.Err()
+			}
+		}
+	}
+	if w.Store != nil {
+		if err := w.Store.SaveTagState(next); err != nil {
+			return events, err
+		}
+	}
+	w.state = next
+	if w.OnEvent != nil {
+		for _, e := range events {
+			w.OnEvent(e)
+		}
+	}
+	return events, firstErr
+}
+
+func diffTagMaps(category string, prev, cur map[string]*Tag, now time.Time) []*TagEvent {
+	var events []*TagEvent
+	for id, t := range cur {
+		p, ok := prev[id]
+		if !ok {
+			events = append(events, &TagEvent{Type: TagAdded, CategoryExternalID: category, ExternalID: id, Tag: t, Time: now})
+			continue
+		}
+		if fields := DiffTags(p, t); len(fields) > 0 {
+			events = append(events, &TagEvent{Type: TagModified, CategoryExternalID: category, ExternalID: id, Tag: t, Previous: p, Fields: fields, Time: now})
+		}
+	}
+	for id, p := range prev {
+		if _, ok := cur[id]; !ok {
+			events = append(events, &TagEvent{Type: TagRemoved, CategoryExternalID: category, ExternalID: id, Previous: p, Time: now})
+		}
+	}
+	return events
+}
+
+// Run polls right away and then every Interval until ctx is done
+func (w *TagWatcher) Run(ctx context.Context) error {
+	return runEvery(ctx, w.Interval, func(ctx context.Context) error {
+		_, err := w.Poll(ctx)
+		return err
+	}, w.OnError)
+}
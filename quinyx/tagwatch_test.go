@@ -0,0 +1,90 @@
+package quinyx
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func eventKinds(events []*TagEvent) []string {
+	var kinds []string
+	for _, e := range events {
+		kinds = append(kinds, string(e.Type)+" "+e.CategoryExternalID+"/"+e.ExternalID)
+	}
+	return kinds
+}
+
+func TestTagWatcher(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	tags := `[{"externalId":"a","name":"A"},{"externalId":"b","name":"B"}]`
+	mux.HandleFunc("/tags/categories", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"externalId":"cc"}]`)
+	})
+	mux.HandleFunc("/tags/categories/cc/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, tags)
+	})
+
+	dir, err := ioutil.TempDir("", "tagwatch")
+	assert.NilError(t, err)
+	defer os.RemoveAll(dir)
+	store := &FileTagStateStore{Path: filepath.Join(dir, "state", "tags.json")}
+
+	ch := make(chan *TagEvent, 10)
+	w := &TagWatcher{Tags: client.Tags, Store: store, Events: ch}
+	events, err := w.Poll(context.Background())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"added cc/a", "added cc/b"}, eventKinds(events))
+	assert.Equal(t, 2, len(ch))
+
+	// A restarted watcher only sees what changed meanwhile
+	tags = `[{"externalId":"a","name":"A2"},{"externalId":"c","name":"C"}]`
+	var seen []*TagEvent
+	w = &TagWatcher{Tags: client.Tags, Store: store, Categories: []string{"cc"}, OnEvent: func(e *TagEvent) { seen = append(seen, e) }}
+	events, err = w.Poll(context.Background())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"modified cc/a", "removed cc/b", "added cc/c"}, eventKinds(seen))
+	assert.Equal(t, "Name: A -> A2", events[0].Fields[0].String())
+	assert.Equal(t, "B", *events[1].Previous.Name)
+
+	events, err = w.Poll(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, 0, len(events))
+}
+
+func TestTagWatcherCanceledSend(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/tags/categories/cc/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"externalId":"a"},{"externalId":"b"}]`)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan *TagEvent)
+	var seen []*TagEvent
+	w := &TagWatcher{Tags: client.Tags, Categories: []string{"cc"}, Events: ch, OnEvent: func(e *TagEvent) { seen = append(seen, e) }}
+	go func() {
+		<-ch
+		cancel()
+	}()
+	// The second send blocks until the reader cancels, nothing is handed to OnEvent
+	_, err := w.Poll(ctx)
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 0, len(seen))
+
+	ch = make(chan *TagEvent, 10)
+	w.Events = ch
+	events, err := w.Poll(context.Background())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []string{"added cc/a", "added cc/b"}, eventKinds(events))
+	assert.DeepEqual(t, []string{"added cc/a", "added cc/b"}, eventKinds(seen))
+	assert.Equal(t, 2, len(ch))
+}
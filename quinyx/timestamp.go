@@ -14,12 +14,12 @@ func (t Timestamp) String() string {
 	return t.Time.String()
 }
 
-// UnmarshalJSON into a Timestamp
+// UnmarshalJSON into a Timestamp, epoch values are returned in UTC
 func (t *Timestamp) UnmarshalJSON(data []byte) (err error) {
 	str := string(data)
 	i, err := strconv.ParseInt(str, 10, 64)
 	if err == nil {
-		t.Time = time.Unix(i, 0)
+		t.Time = time.Unix(i, 0).UTC()
 	} else {
 		t.Time, err = time.Parse(`"`+time.RFC3339+`"`, str)
 	}
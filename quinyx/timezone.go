@@ -0,0 +1,161 @@
+package quinyx
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrorUnitZoneUnknown is the error returned when the time zone of a unit is neither registered nor resolvable
+var ErrorUnitZoneUnknown = fmt.Errorf("Time zone of unit is unknown")
+
+// ZoneResolver looks up the time zone of a unit, for example in a master data system
+type ZoneResolver func(ctx context.Context, externalUnitID string) (*time.Location, error)
+
+// UnitZones keeps track of the IANA time zone of each unit, so that a day means the
+// local calendar day of the unit and not a UTC day.
+type UnitZones struct {
+	// Resolver, if set, is asked for the zones of units that are not registered. Resolved zones are cached.
+	Resolver ZoneResolver
+	// Default, if set, is used for units that are neither registered nor resolvable
+	Default *time.Location
+
+	mu    sync.RWMutex
+	zones map[string]*time.Location
+}
+
+// NewUnitZones returns an empty UnitZones using resolver for unknown units, resolver may be nil
+func NewUnitZones(resolver ZoneResolver) *UnitZones {
+	return &UnitZones{Resolver: resolver, zones: map[string]*time.Location{}}
+}
+
+// Register sets the time zone of a unit by IANA name, e.g. "Europe/Stockholm"
+func (z *UnitZones) Register(externalUnitID, zone string) error {
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return err
+	}
+	z.RegisterLocation(externalUnitID, loc)
+	return nil
+}
+
+// RegisterLocation sets the time zone of a unit
+func (z *UnitZones) RegisterLocation(externalUnitID string, loc *time.Location) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if z.zones == nil {
+		z.zones = map[string]*time.Location{}
+	}
+	z.zones[externalUnitID] = loc
+}
+
+// Location returns the time zone of a unit
+func (z *UnitZones) Location(ctx context.Context, externalUnitID string) (*time.Location, error) {
+	z.mu.RLock()
+	loc, ok := z.zones[externalUnitID]
+	z.mu.RUnlock()
+	if ok {
+		return loc, nil
+	}
+	if z.Resolver != nil {
+		loc, err := z.Resolver(ctx, externalUnitID)
+		if err != nil {
+			return nil, err
+		}
+		if loc != nil {
+			z.RegisterLocation(externalUnitID, loc)
+			return loc, nil
+		}
+	}
+	if z.Default != nil {
+		return z.Default, nil
+	}
+	return nil, ErrorUnitZoneUnknown
+}
+
+// DayRange returns the options for days local calendar days of the unit starting at
+// the date of day. The range starts and ends at local midnight, so a day can be 23 or
+// 25 hours long when it has a DST transition. The times are sent in UTC.
+func (z *UnitZones) DayRange(ctx context.Context, unit UnitSection, day time.Time, days int) (*RequestRangeOptions, error) {
+	loc, err := z.Location(ctx, unit.ExternalUnitID)
+	if err != nil {
+		return nil, err
+	}
+	start, end := LocalDays(loc, day, days)
+	return &RequestRangeOptions{
+		StartTime:         start.UTC(),
+		EndTime:           end.UTC(),
+		ExternalUnitID:    String(unit.ExternalUnitID),
+		ExternalSectionID: unit.ExternalSectionID,
+	}, nil
+}
+
+// LocalDays returns local midnight in loc on the calendar date of day and local
+// midnight days calendar days later. The date of day is read in its own location.
+func LocalDays(loc *time.Location, day time.Time, days int) (time.Time, time.Time) {
+	y, m, d := day.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc), time.Date(y, m, d+days, 0, 0, 0, 0, loc)
+}
+
+// LocalDay returns local midnight in loc at the start of the day t falls on
+func LocalDay(t time.Time, loc *time.Location) time.Time {
+	start, _ := LocalDays(loc, t.In(loc), 0)
+	return start
+}
+
+// LocalChunks splits the range into consecutive ranges of at most 120 local calendar
+// days in loc, so that chunk boundaries fall on local midnight when StartTime does.
+func (g *RequestRangeOptions) LocalChunks(loc *time.Location) []*RequestRangeOptions {
+	local := *g
+	local.StartTime = g.StartTime.In(loc)
+	chunks := local.chunks()
+	for _, c := range chunks {
+		c.StartTime, c.EndTime = c.StartTime.UTC(), c.EndTime.UTC()
+	}
+	return chunks
+}
+
+// ResampleDaily sums payloads per local calendar day in loc. The result has one payload
+// per day with data, ordered by time and stamped with local midnight. Payloads without
+// timestamp or data are skipped.
+func ResampleDaily(payloads []*Payload, loc *time.Location) []*Payload {
+	sums := map[int64]*Payload{}
+	for _, p := range payloads {
+		if p == nil || p.Timestamp == nil || p.Data == nil {
+			continue
+		}
+		day := LocalDay(p.Timestamp.Time, loc)
+		sum, ok := sums[day.Unix()]
+		if !ok {
+			sum = &Payload{Data: Float64(0), Timestamp: &Timestamp{day}}
+			sums[day.Unix()] = sum
+		}
+		*sum.Data += *p.Data
+	}
+	out := make([]*Payload, 0, len(sums))
+	for _, p := range sums {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Timestamp.Before(out[j].Timestamp.Time) })
+	return out
+}
+
+// ResampleAggregatedDaily sums aggregated payloads per local calendar day in loc, by
+// the day their StartTime falls on. Each result spans its local day, see ResampleDaily.
+func ResampleAggregatedDaily(payloads []*AggregatedPayload, loc *time.Location) []*AggregatedPayload {
+	in := make([]*Payload, 0, len(payloads))
+	for _, p := range payloads {
+		if p != nil {
+			in = append(in, &Payload{Data: p.Data, Timestamp: p.StartTime})
+		}
+	}
+	days := ResampleDaily(in, loc)
+	out := make([]*AggregatedPayload, len(days))
+	for i, d := range days {
+		_, end := LocalDays(loc, d.Timestamp.Time, 1)
+		out[i] = &AggregatedPayload{Data: d.Data, StartTime: d.Timestamp, EndTime: &Timestamp{end}}
+	}
+	return out
+}
@@ -0,0 +1,82 @@
+package quinyx
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func TestUnitZonesDayRange(t *testing.T) {
+	resolved := 0
+	z := NewUnitZones(func(ctx context.Context, id string) (*time.Location, error) {
+		resolved++
+		if id == "london" {
+			return time.LoadLocation("Europe/London")
+		}
+		return nil, nil
+	})
+	assert.NilError(t, z.Register("stockholm", "Europe/Stockholm"))
+	assert.Assert(t, z.Register("x", "Not/AZone") != nil)
+
+	// The last Sunday of October 2020 is 25 hours long in Stockholm
+	day := time.Date(2020, time.October, 25, 12, 0, 0, 0, time.UTC)
+	o, err := z.DayRange(context.Background(), UnitSection{ExternalUnitID: "stockholm"}, day, 1)
+	assert.NilError(t, err)
+	assert.Equal(t, time.Date(2020, time.October, 24, 22, 0, 0, 0, time.UTC), o.StartTime)
+	assert.Equal(t, time.Date(2020, time.October, 25, 23, 0, 0, 0, time.UTC), o.EndTime)
+	assert.Equal(t, "stockholm", *o.ExternalUnitID)
+
+	o, err = z.DayRange(context.Background(), UnitSection{ExternalUnitID: "london", ExternalSectionID: String("s")}, day, 2)
+	assert.NilError(t, err)
+	assert.Equal(t, time.Date(2020, time.October, 24, 23, 0, 0, 0, time.UTC), o.StartTime)
+	assert.Equal(t, time.Date(2020, time.October, 27, 0, 0, 0, 0, time.UTC), o.EndTime)
+	_, err = z.Location(context.Background(), "london")
+	assert.NilError(t, err)
+	assert.Equal(t, 1, resolved)
+
+	_, err = z.Location(context.Background(), "unknown")
+	assert.Equal(t, ErrorUnitZoneUnknown, err)
+	z.Default = time.UTC
+	loc, err := z.Location(context.Background(), "unknown")
+	assert.NilError(t, err)
+	assert.Equal(t, time.UTC, loc)
+}
+
+func TestLocalChunks(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/Stockholm")
+	start, end := LocalDays(loc, time.Date(2020, time.September, 1, 0, 0, 0, 0, time.UTC), 150)
+	o := &RequestRangeOptions{StartTime: start.UTC(), EndTime: end.UTC(), ExternalUnitID: String("u")}
+	chunks := o.LocalChunks(loc)
+	assert.Equal(t, 2, len(chunks))
+	// 120 local days later is past the DST change, so the boundary is 23:00 UTC, not 22:00
+	assert.Equal(t, time.Date(2020, time.December, 29, 23, 0, 0, 0, time.UTC), chunks[0].EndTime)
+	assert.Equal(t, chunks[0].EndTime, chunks[1].StartTime)
+	assert.Equal(t, time.UTC, chunks[1].StartTime.Location())
+	assert.Equal(t, end.UTC(), chunks[1].EndTime)
+}
+
+func TestResampleDaily(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/Stockholm")
+	start := time.Date(2020, time.October, 24, 21, 0, 0, 0, time.UTC)
+	payloads := testSeries(start, time.Hour, Float64(1), Float64(2), Float64(3), nil)
+	payloads = append(payloads, &Payload{Data: Float64(4), Timestamp: &Timestamp{start.Add(27 * time.Hour)}})
+	days := ResampleDaily(payloads, loc)
+	assert.Equal(t, 3, len(days))
+	assert.Equal(t, float64(1), *days[0].Data)
+	assert.Equal(t, float64(5), *days[1].Data)
+	assert.Equal(t, float64(4), *days[2].Data)
+	assert.Assert(t, days[1].Timestamp.Equal(Timestamp{time.Date(2020, time.October, 25, 0, 0, 0, 0, loc)}))
+
+	agg := ResampleAggregatedDaily([]*AggregatedPayload{{Data: Float64(2), StartTime: &Timestamp{start.Add(2 * time.Hour)}}}, loc)
+	assert.Equal(t, 25*time.Hour, agg[0].EndTime.Sub(agg[0].StartTime.Time))
+}
+
+func TestTimestampEpochIsUTC(t *testing.T) {
+	var ts Timestamp
+	assert.NilError(t, json.Unmarshal([]byte("1602489600"), &ts))
+	assert.Equal(t, time.UTC, ts.Location())
+	assert.Equal(t, time.Date(2020, time.October, 12, 8, 0, 0, 0, time.UTC), ts.Time)
+}
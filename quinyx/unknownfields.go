@@ -0,0 +1,194 @@
+package quinyx
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// UnknownField is a JSON field of a response that the model it was decoded into does not have
+type UnknownField struct {
+	// Type is the name of the model, like Tag
+	Type string
+	// Field is the JSON name of the field
+	Field string
+	// Path is where the field was in the response, like [0].coordinates[1].altitude
+	Path string
+}
+
+func (f UnknownField) String() string {
+	return f.Type + "." + f.Field + " at " + f.Path
+}
+
+// UnknownFieldsError is the error returned by Do in strict mode, see Client.StrictFields,
+// when a response has fields the models do not have
+type UnknownFieldsError struct {
+	Response *Response
+	Fields   []UnknownField
+}
+
+func (e *UnknownFieldsError) Error() string {
+	fields := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		fields[i] = f.String()
+	}
+	return fmt.Sprintf("%v %v: unknown fields %s", e.Response.Request.Method, sanitizeURL(e.Response.Request.URL), strings.Join(fields, ", "))
+}
+
+// UnknownFields returns the unknown fields seen so far by the model name, like Tag,
+// when Client.OnUnknownFields or Client.StrictFields is set
+func (c *Client) UnknownFields() map[string][]string {
+	c.unknownMu.Lock()
+	defer c.unknownMu.Unlock()
+	all := make(map[string][]string, len(c.unknownFields))
+	for typ, fields := range c.unknownFields {
+		all[typ] = sortedKeys(fields)
+	}
+	return all
+}
+
+// checkUnknownFields records the fields of the body that v does not have, reports
+// them to OnUnknownFields and returns an *UnknownFieldsError in strict mode
+func (c *Client) checkUnknownFields(response *Response, body []byte, v interface{}) error {
+	var raw interface{}
+	if err := json.Unmarshal(body, &raw); err != nil {
+		return nil
+	}
+	fields := unknownFields(raw, reflect.TypeOf(v), "", nil)
+	if len(fields) == 0 {
+		return nil
+	}
+	c.unknownMu.Lock()
+	if c.unknownFields == nil {
+		c.unknownFields = map[string]map[string]bool{}
+	}
+	for _, f := range fields {
+		if c.unknownFields[f.Type] == nil {
+			c.unknownFields[f.Type] = map[string]bool{}
+		}
+		c.unknownFields[f.Type][f.Field] = true
+	}
+	c.unknownMu.Unlock()
+	if c.OnUnknownFields != nil {
+		c.OnUnknownFields(response, fields)
+	}
+	if c.StrictFields {
+		return &UnknownFieldsError{Response: response, Fields: fields}
+	}
+	return nil
+}
+
+// fieldsShaper is implemented by models with their own UnmarshalJSON to tell which
+// part of the raw JSON is decoded into which type
+type fieldsShaper interface {
+	fieldsShape(raw interface{}) (interface{}, reflect.Type)
+}
+
+var (
+	fieldsShaperType    = reflect.TypeOf((*fieldsShaper)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+// unknownFields walks raw alongside t the way encoding/json decodes it and appends
+// every object key that t has no field for. Types with their own UnmarshalJSON are
+// not looked into unless they are a fieldsShaper.
+func unknownFields(raw interface{}, t reflect.Type, path string, found []UnknownField) []UnknownField {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	pt := reflect.PtrTo(t)
+	if pt.Implements(fieldsShaperType) {
+		sub, st := reflect.Zero(pt).Interface().(fieldsShaper).fieldsShape(raw)
+		return unknownFields(sub, st, path, found)
+	}
+	if pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType) {
+		return found
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return found
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ft, ok := fields[k]
+			if !ok {
+				ft, ok = fields[strings.ToLower(k)]
+			}
+			if !ok {
+				found = append(found, UnknownField{Type: t.Name(), Field: k, Path: path + "." + k})
+				continue
+			}
+			found = unknownFields(obj[k], ft, path+"."+k, found)
+		}
+	case reflect.Slice, reflect.Array:
+		list, ok := raw.([]interface{})
+		if !ok {
+			return found
+		}
+		for i, e := range list {
+			found = unknownFields(e, t.Elem(), fmt.Sprintf("%s[%d]", path, i), found)
+		}
+	case reflect.Map:
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return found
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			found = unknownFields(obj[k], t.Elem(), path+"."+k, found)
+		}
+	}
+	return found
+}
+
+// jsonFields returns the types of the fields encoding/json decodes into t by their
+// name, also by the lower case name for its case-insensitive matching
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" {
+			et := f.Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				for k, v := range jsonFields(et) {
+					if _, ok := fields[k]; !ok {
+						fields[k] = v
+					}
+				}
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue // unexported
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+		if _, ok := fields[strings.ToLower(name)]; !ok {
+			fields[strings.ToLower(name)] = f.Type
+		}
+	}
+	return fields
+}
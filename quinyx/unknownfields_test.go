@@ -0,0 +1,66 @@
+package quinyx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"gotest.tools/assert"
+)
+
+func TestUnknownFieldsWalk(t *testing.T) {
+	var raw interface{}
+	assert.NilError(t, json.Unmarshal([]byte(`[{"AMOUNT":1,"externalId":"e","priority":2,
+		"startTime":{"hour":1,"tz":"x"},"shiftTypes":[{"externalShiftTypeId":"s","color":"red"}],"weekdays":["0"]}]`), &raw))
+	got := unknownFields(raw, reflect.TypeOf(&[]*DynamicRule{}), "", nil)
+	var fields []string
+	for _, f := range got {
+		fields = append(fields, f.String())
+	}
+	assert.DeepEqual(t, []string{
+		"DynamicRule.priority at [0].priority",
+		"ShiftType.color at [0].shiftTypes[0].color",
+		"LocalTime.tz at [0].startTime.tz",
+	}, fields)
+}
+
+func TestOnUnknownFields(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+
+	mux.HandleFunc("/tags/categories/cc/tags", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"content":[{"externalId":"a","color":"red","coordinates":[{"latitude":1,"longitude":2,"radius":3,"altitude":4}],
+			"startDate":"2021-01-01T00:00:00Z"}],"number":0,"totalPages":1,"last":true,"sort":{"sorted":false}}`)
+	})
+	mux.HandleFunc("/tags/categories/cc", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"externalId":"cc","tagType":"PROJECT"}`)
+	})
+
+	var seen []UnknownField
+	client.OnUnknownFields = func(resp *Response, fields []UnknownField) {
+		assert.Equal(t, "/tags/categories/cc/tags", resp.Request.URL.Path)
+		seen = append(seen, fields...)
+	}
+	tags, _, err := client.Tags.GetAllTags(context.Background(), "cc")
+	assert.NilError(t, err)
+	assert.Equal(t, "a", *tags[0].ExternalID)
+	assert.DeepEqual(t, []UnknownField{
+		{Type: "Tag", Field: "color", Path: "[0].color"},
+		{Type: "Coordinate", Field: "altitude", Path: "[0].coordinates[0].altitude"},
+	}, seen)
+	_, _, err = client.Tags.GetCategory(context.Background(), "cc")
+	assert.NilError(t, err)
+	assert.Equal(t, 2, len(seen))
+	assert.DeepEqual(t, map[string][]string{"Tag": {"color"}, "Coordinate": {"altitude"}}, client.UnknownFields())
+
+	client.OnUnknownFields = nil
+	client.StrictFields = true
+	_, _, err = client.Tags.GetAllTags(context.Background(), "cc")
+	uerr, ok := err.(*UnknownFieldsError)
+	assert.Assert(t, ok, "%T", err)
+	assert.Equal(t, 2, len(uerr.Fields))
+	assert.ErrorContains(t, err, "unknown fields Tag.color at [0].color, Coordinate.altitude")
+}
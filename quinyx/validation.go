@@ -0,0 +1,359 @@
+package quinyx
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Severity of a ValidationIssue
+type Severity int
+
+// Severities
+const (
+	SeverityInfo Severity = iota + 1
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// ValidationIssue codes
+const (
+	IssueMissingTimestamp    = "missing_timestamp"
+	IssueDuplicateTimestamp  = "duplicate_timestamp"
+	IssueMisalignedTimestamp = "misaligned_timestamp"
+	IssueNilData             = "nil_data"
+	IssueNegativeValue       = "negative_value"
+	IssueGap                 = "gap"
+	IssueZeroDay             = "zero_day"
+	IssueOutlier             = "outlier"
+)
+
+// defaultSeverities are used for codes missing in UploadValidator.Severities
+var defaultSeverities = map[string]Severity{
+	IssueMissingTimestamp:    SeverityError,
+	IssueDuplicateTimestamp:  SeverityError,
+	IssueMisalignedTimestamp: SeverityError,
+	IssueNilData:             SeverityWarning,
+	IssueNegativeValue:       SeverityError,
+	IssueGap:                 SeverityWarning,
+	IssueZeroDay:             SeverityWarning,
+	IssueOutlier:             SeverityWarning,
+}
+
+const (
+	defaultOutlierWindow    = 96
+	defaultOutlierThreshold = 6
+	minOutlierHistory       = 8
+)
+
+// ValidationIssue is a single problem found in upload data
+type ValidationIssue struct {
+	Severity                   Severity
+	Code                       string
+	ExternalForecastVariableID string
+	ExternalUnitID             string
+	ExternalSectionID          *string
+	// Timestamp of the offending payload, nil when it has none
+	Timestamp *time.Time
+	Message   string
+}
+
+func (i *ValidationIssue) String() string {
+	where := i.ExternalForecastVariableID + " " + i.ExternalUnitID
+	if i.ExternalSectionID != nil {
+		where += "/" + *i.ExternalSectionID
+	}
+	if i.Timestamp != nil {
+		where += " " + i.Timestamp.Format(time.RFC3339)
+	}
+	return fmt.Sprintf("%s %s: %s: %s", i.Severity, i.Code, where, i.Message)
+}
+
+// ValidationReport is the result of validating upload data
+type ValidationReport struct {
+	Issues []*ValidationIssue
+}
+
+// MaxSeverity returns the highest severity in the report, 0 when there are no issues
+func (r *ValidationReport) MaxSeverity() Severity {
+	var max Severity
+	for _, i := range r.Issues {
+		if i.Severity > max {
+			max = i.Severity
+		}
+	}
+	return max
+}
+
+// Count returns the number of issues of severity s
+func (r *ValidationReport) Count(s Severity) int {
+	n := 0
+	for _, i := range r.Issues {
+		if i.Severity == s {
+			n++
+		}
+	}
+	return n
+}
+
+// ValidationError is returned by the upload methods when validation blocks an upload
+type ValidationError struct {
+	Report *ValidationReport
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "upload blocked by validation: %d errors, %d warnings", e.Report.Count(SeverityError), e.Report.Count(SeverityWarning))
+	for i, issue := range e.Report.Issues {
+		if i == 5 {
+			fmt.Fprintf(&b, "; and %d more", len(e.Report.Issues)-i)
+			break
+		}
+		b.WriteString("; ")
+		b.WriteString(issue.String())
+	}
+	return b.String()
+}
+
+// UploadValidator checks forecast data before it is uploaded. Set it as
+// Client.UploadValidator to run it on UploadBudgetData and UploadPredictedData.
+type UploadValidator struct {
+	// Resolution is the expected distance between timestamps. Zero disables
+	// the alignment and gap checks.
+	Resolution time.Duration
+	// Location is the time zone slots are aligned to, like the unit's zone from
+	// UnitZones. It defaults to the location of each timestamp. Resolutions of a
+	// day or more are aligned to local midnight and stepped by calendar days.
+	Location *time.Location
+	// AllowNegative disables the negative value check
+	AllowNegative bool
+	// OutlierWindow is the number of trailing values an outlier is measured against, defaults to 96
+	OutlierWindow int
+	// OutlierThreshold is the robust z-score above which a value is an outlier, defaults to 6.
+	// A negative threshold disables the check.
+	OutlierThreshold float64
+	// Severities overrides the severity of issue codes
+	Severities map[string]Severity
+	// BlockAt is the lowest severity that blocks an upload, defaults to SeverityError
+	BlockAt Severity
+	// OnReport, if set, receives every report, including the ones that do not block
+	OnReport func(*ValidationReport)
+}
+
+// ValidateDataProviderInputs validates every series of dil
+func (v *UploadValidator) ValidateDataProviderInputs(dil *DataProviderInputList) *ValidationReport {
+	r := &ValidationReport{}
+	if dil == nil {
+		return r
+	}
+	for _, in := range dil.DataProviderInputs {
+		v.validateSeries(r, stringValue(in.ExternalForecastVariableID), stringValue(in.ExternalUnitID), in.ExternalSectionID, in.DataPayload)
+	}
+	return r
+}
+
+// ValidatePredictions validates every series of inlist
+func (v *UploadValidator) ValidatePredictions(inlist *PredictedDataInputList) *ValidationReport {
+	r := &ValidationReport{}
+	if inlist == nil {
+		return r
+	}
+	for _, fp := range inlist.ForecastPredictions {
+		v.validateSeries(r, stringValue(fp.ExternalForecastVariableID), stringValue(fp.ExternalUnitID), fp.ExternalSectionID, fp.Payloads)
+	}
+	return r
+}
+
+// checkDataProviderInputs validates dil, returning a *ValidationError when the upload should be blocked
+func (v *UploadValidator) checkDataProviderInputs(dil *DataProviderInputList) error {
+	if v == nil {
+		return nil
+	}
+	return v.enforce(v.ValidateDataProviderInputs(dil))
+}
+
+// checkPredictions validates inlist, returning a *ValidationError when the upload should be blocked
+func (v *UploadValidator) checkPredictions(inlist *PredictedDataInputList) error {
+	if v == nil {
+		return nil
+	}
+	return v.enforce(v.ValidatePredictions(inlist))
+}
+
+func (v *UploadValidator) enforce(r *ValidationReport) error {
+	if v.OnReport != nil {
+		v.OnReport(r)
+	}
+	blockAt := v.BlockAt
+	if blockAt == 0 {
+		blockAt = SeverityError
+	}
+	if len(r.Issues) > 0 && r.MaxSeverity() >= blockAt {
+		return &ValidationError{Report: r}
+	}
+	return nil
+}
+
+func (v *UploadValidator) severity(code string) Severity {
+	if s, ok := v.Severities[code]; ok {
+		return s
+	}
+	return defaultSeverities[code]
+}
+
+func (v *UploadValidator) validateSeries(r *ValidationReport, variable, unit string, section *string, payloads []*Payload) {
+	add := func(code string, ts *time.Time, format string, args ...interface{}) {
+		r.Issues = append(r.Issues, &ValidationIssue{
+			Severity:                   v.severity(code),
+			Code:                       code,
+			ExternalForecastVariableID: variable,
+			ExternalUnitID:             unit,
+			ExternalSectionID:          section,
+			Timestamp:                  ts,
+			Message:                    fmt.Sprintf(format, args...),
+		})
+	}
+
+	// Work on a sorted copy, the caller's payload order is left untouched
+	points := make([]*Payload, 0, len(payloads))
+	for i, p := range payloads {
+		if p == nil || p.Timestamp == nil {
+			add(IssueMissingTimestamp, nil, "payload %d has no timestamp", i)
+			continue
+		}
+		points = append(points, p)
+	}
+	sort.SliceStable(points, func(i, j int) bool { return points[i].Timestamp.Before(points[j].Timestamp.Time) })
+
+	var zeroStart *time.Time
+	var zeroEnd time.Time
+	flushZeros := func() {
+		if zeroStart != nil && zeroEnd.Add(v.Resolution).Sub(*zeroStart) >= 24*time.Hour {
+			add(IssueZeroDay, zeroStart, "only zeros until %s", zeroEnd.Format(time.RFC3339))
+		}
+		zeroStart = nil
+	}
+
+	var history []float64
+	for i, p := range points {
+		ts := p.Timestamp.Time
+		if i > 0 {
+			prev := points[i-1].Timestamp.Time
+			d := ts.Sub(prev)
+			if v.Resolution >= 24*time.Hour && d != 0 {
+				d = v.wallClock(ts).Sub(v.wallClock(prev))
+			}
+			switch {
+			case d == 0:
+				add(IssueDuplicateTimestamp, &ts, "timestamp appears more than once")
+				continue
+			case v.Resolution > 0 && d > v.Resolution:
+				add(IssueGap, &ts, "%d slots missing since %s", int((d-1)/v.Resolution), prev.Format(time.RFC3339))
+			}
+		}
+		if v.Resolution > 0 && !v.aligned(ts) {
+			add(IssueMisalignedTimestamp, &ts, "timestamp is not aligned to %v", v.Resolution)
+		}
+
+		if p.Data == nil {
+			add(IssueNilData, &ts, "data is nil")
+			flushZeros()
+			continue
+		}
+		value := *p.Data
+		if value < 0 && !v.AllowNegative {
+			add(IssueNegativeValue, &ts, "negative value %v", value)
+		}
+		if value == 0 {
+			if zeroStart == nil {
+				start := ts // a copy, not a pointer into the caller's payload
+				zeroStart = &start
+			}
+			zeroEnd = ts
+		} else {
+			flushZeros()
+		}
+		// Zeros are left to the zero day check, a closed store is not an outlier
+		if z, ok := v.outlierScore(history, value); ok && value != 0 {
+			add(IssueOutlier, &ts, "value %v deviates %.1f robust standard deviations from the trailing median", value, z)
+		}
+		history = append(history, value)
+	}
+	flushZeros()
+}
+
+// outlierScore returns the robust z-score of value against the trailing window of history
+func (v *UploadValidator) outlierScore(history []float64, value float64) (float64, bool) {
+	threshold := v.OutlierThreshold
+	if threshold < 0 {
+		return 0, false
+	}
+	if threshold == 0 {
+		threshold = defaultOutlierThreshold
+	}
+	window := v.OutlierWindow
+	if window <= 0 {
+		window = defaultOutlierWindow
+	}
+	if len(history) < minOutlierHistory {
+		return 0, false
+	}
+	if len(history) > window {
+		history = history[len(history)-window:]
+	}
+	med := median(history)
+	deviations := make([]float64, len(history))
+	for i, h := range history {
+		deviations[i] = math.Abs(h - med)
+	}
+	mad := median(deviations)
+	if mad == 0 {
+		return 0, false
+	}
+	// 0.6745 scales the MAD to the standard deviation of a normal distribution
+	z := 0.6745 * math.Abs(value-med) / mad
+	return z, z > threshold
+}
+
+func median(values []float64) float64 {
+	s := append([]float64(nil), values...)
+	sort.Float64s(s)
+	n := len(s)
+	if n%2 == 1 {
+		return s[n/2]
+	}
+	return (s[n/2-1] + s[n/2]) / 2
+}
+
+// wallClock returns the local date and clock time of t in Location as a UTC time,
+// so durations between them ignore DST changes
+func (v *UploadValidator) wallClock(t time.Time) time.Time {
+	if v.Location != nil {
+		t = t.In(v.Location)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// aligned reports whether t starts a slot on the local clock
+func (v *UploadValidator) aligned(t time.Time) bool {
+	align := v.Resolution
+	if align > 24*time.Hour {
+		align = 24 * time.Hour
+	}
+	wall := v.wallClock(t)
+	return wall.Equal(wall.Truncate(align))
+}
@@ -0,0 +1,146 @@
+package quinyx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func testSeries(start time.Time, step time.Duration, values ...*float64) []*Payload {
+	payloads := make([]*Payload, len(values))
+	for i, v := range values {
+		payloads[i] = &Payload{Data: v, Timestamp: &Timestamp{start.Add(time.Duration(i) * step)}}
+	}
+	return payloads
+}
+
+func issueCodes(r *ValidationReport) []string {
+	var codes []string
+	for _, i := range r.Issues {
+		codes = append(codes, i.Code)
+	}
+	return codes
+}
+
+func TestUploadValidatorChecks(t *testing.T) {
+	start := time.Date(2020, time.October, 12, 0, 0, 0, 0, time.UTC)
+	payloads := testSeries(start, 15*time.Minute, Float64(1), Float64(-2), nil)
+	payloads = append(payloads,
+		&Payload{Data: Float64(1), Timestamp: &Timestamp{start}},                        // duplicate
+		&Payload{Data: Float64(1), Timestamp: &Timestamp{start.Add(95 * time.Minute)}},  // misaligned
+		&Payload{Data: Float64(1), Timestamp: &Timestamp{start.Add(120 * time.Minute)}}, // gap
+		&Payload{Data: Float64(1)},
+	)
+	v := &UploadValidator{Resolution: 15 * time.Minute}
+	r := v.ValidateDataProviderInputs(&DataProviderInputList{DataProviderInputs: []DataProviderInput{{
+		ExternalForecastVariableID: String("v"),
+		ExternalUnitID:             String("u"),
+		DataPayload:                payloads,
+	}}})
+	assert.DeepEqual(t, []string{
+		IssueMissingTimestamp,
+		IssueDuplicateTimestamp,
+		IssueNegativeValue,
+		IssueNilData,
+		IssueGap,
+		IssueMisalignedTimestamp,
+		IssueGap,
+	}, issueCodes(r))
+	assert.Equal(t, SeverityError, r.MaxSeverity())
+	assert.Equal(t, "warning gap: v u 2020-10-12T02:00:00Z: 1 slots missing since 2020-10-12T01:35:00Z", r.Issues[6].String())
+}
+
+func TestUploadValidatorLocalDays(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Stockholm")
+	assert.NilError(t, err)
+	// Local midnights across the end of DST, as Quinyx returns them in UTC
+	var payloads []*Payload
+	for d := 23; d <= 28; d++ {
+		ts := time.Date(2020, time.October, d, 0, 0, 0, 0, loc).UTC()
+		payloads = append(payloads, &Payload{Data: Float64(float64(d)), Timestamp: &Timestamp{ts}})
+	}
+	series := func(payloads []*Payload) *DataProviderInputList {
+		return &DataProviderInputList{DataProviderInputs: []DataProviderInput{{
+			ExternalForecastVariableID: String("v"),
+			ExternalUnitID:             String("u"),
+			DataPayload:                payloads,
+		}}}
+	}
+
+	v := &UploadValidator{Resolution: 24 * time.Hour, Location: loc}
+	r := v.ValidateDataProviderInputs(series(payloads))
+	assert.DeepEqual(t, []string(nil), issueCodes(r))
+
+	// Without Location the timestamps are aligned in their own zone
+	local := make([]*Payload, len(payloads))
+	for i, p := range payloads {
+		local[i] = &Payload{Data: p.Data, Timestamp: &Timestamp{p.Timestamp.In(loc)}}
+	}
+	r = (&UploadValidator{Resolution: 24 * time.Hour}).ValidateDataProviderInputs(series(local))
+	assert.DeepEqual(t, []string(nil), issueCodes(r))
+
+	noon := time.Date(2020, time.October, 30, 12, 0, 0, 0, loc)
+	gap := append(payloads, &Payload{Data: Float64(1), Timestamp: &Timestamp{noon.UTC()}})
+	r = v.ValidateDataProviderInputs(series(gap))
+	assert.DeepEqual(t, []string{IssueGap, IssueMisalignedTimestamp}, issueCodes(r))
+	assert.Equal(t, "warning gap: v u 2020-10-30T11:00:00Z: 2 slots missing since 2020-10-27T23:00:00Z", r.Issues[0].String())
+}
+
+func TestUploadValidatorZeroDayAndOutlier(t *testing.T) {
+	start := time.Date(2020, time.October, 12, 0, 0, 0, 0, time.UTC)
+	var values []*float64
+	for i := 0; i < 24; i++ {
+		values = append(values, Float64(float64(10+i%3)))
+	}
+	values = append(values, Float64(1000))
+	for i := 0; i < 24; i++ {
+		values = append(values, Float64(0))
+	}
+	v := &UploadValidator{Resolution: time.Hour}
+	payloads := testSeries(start, time.Hour, values...)
+	r := v.ValidatePredictions(&PredictedDataInputList{ForecastPredictions: []ForecastPrediction{{
+		ExternalForecastVariableID: String("v"),
+		ExternalUnitID:             String("u"),
+		Payloads:                   payloads,
+	}}})
+	assert.DeepEqual(t, []string{IssueOutlier, IssueZeroDay}, issueCodes(r))
+	assert.Equal(t, start.Add(24*time.Hour), *r.Issues[0].Timestamp)
+	assert.Equal(t, start.Add(25*time.Hour), *r.Issues[1].Timestamp)
+	// The issue does not change with the payload
+	payloads[25].Timestamp.Time = start
+	assert.Equal(t, start.Add(25*time.Hour), *r.Issues[1].Timestamp)
+	assert.Equal(t, SeverityWarning, r.MaxSeverity())
+}
+
+func TestUploadValidatorBlocksUpload(t *testing.T) {
+	client, mux, _, teardown := setup()
+	defer teardown()
+	calls := 0
+	mux.HandleFunc("/forecasts/budget-data", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	})
+
+	var reports []*ValidationReport
+	client.UploadValidator = &UploadValidator{OnReport: func(r *ValidationReport) { reports = append(reports, r) }}
+	dil := &DataProviderInputList{DataProviderInputs: []DataProviderInput{{
+		ExternalForecastVariableID: String("v"),
+		ExternalUnitID:             String("u"),
+		DataPayload:                testSeries(time.Now(), time.Hour, Float64(1), Float64(-1)),
+	}}}
+	_, err := client.Forecast.UploadBudgetData(context.Background(), false, dil)
+	var verr *ValidationError
+	assert.Assert(t, errors.As(err, &verr))
+	assert.ErrorContains(t, err, "upload blocked by validation: 1 errors, 0 warnings")
+	assert.Equal(t, 0, calls)
+
+	// Downgrading the severity lets the upload through
+	client.UploadValidator.Severities = map[string]Severity{IssueNegativeValue: SeverityWarning}
+	_, err = client.Forecast.UploadBudgetData(context.Background(), false, dil)
+	assert.NilError(t, err)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 2, len(reports))
+}